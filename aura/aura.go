@@ -9,9 +9,10 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"sync"
+	"time"
 
-	"github.com/hashicorp/go-retryablehttp"
-	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/clientcredentials"
 )
 
@@ -19,97 +20,248 @@ const endpoint = "https://api.neo4j.io"
 const retries = 0
 const version = "v1"
 
+// Sentinel errors matching an AuraError with the corresponding status code,
+// for use with errors.Is.
+var (
+	ErrValidation   = errors.New("invalid request")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrRateLimited  = errors.New("rate limited")
+	ErrServer       = errors.New("server error")
+)
+
+// ErrorDetail is a single entry of the errors returned in the body of a
+// failed request to the Aura API.
+type ErrorDetail struct {
+	Message string `json:"message"`
+	Reason  string `json:"reason"`
+	Field   string `json:"field"`
+}
+
 // AuraError is used to inject the request ID used by Neo4J support into
 // error messages when possible and include the response body.
 type AuraError struct {
-	requestID string
-	Err       error
-	response  *http.Response
+	StatusCode int           // HTTP status code of the response
+	RequestID  string        // ID Neo4J support can use to identify the request
+	Errors     []ErrorDetail // Errors reported in the response body, if any
+	Body       string        // The raw response body
+	Err        error
 }
 
 func (e *AuraError) Error() string {
 	return fmt.Sprintf("Aura API error: %v\nAura request ID: %v\nResponse body: %v",
-		e.Err, e.requestID, responseBodyToString(e.response))
+		e.Err, e.RequestID, e.Body)
+}
+
+func (e *AuraError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the status code of the error corresponds to the given sentinel error.
+func (e *AuraError) Is(target error) bool {
+	switch target {
+	case ErrValidation:
+		return e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusUnprocessableEntity
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServer:
+		return e.StatusCode >= http.StatusInternalServerError
+	}
+	return false
 }
 
 // newAuraError returns an AuraError with the requestID set to the
 // X-Request-Id header value of the given response. This requestID
 // can be used by Neo4J staff to identify specific requests.
+// The response body is read and closed, parsing any errors reported by Aura.
 func newAuraError(err error, resp *http.Response) *AuraError {
-	return &AuraError{
-		requestID: resp.Header.Get("X-Request-Id"),
-		Err:       err,
-		response:  resp,
+	e := &AuraError{Err: err}
+	if resp == nil {
+		return e
 	}
+	e.StatusCode = resp.StatusCode
+	e.RequestID = resp.Header.Get("X-Request-Id")
+	defer resp.Body.Close()
+	body, readErr := io.ReadAll(resp.Body)
+	if readErr != nil {
+		return e
+	}
+	e.Body = string(body)
+	var errResp struct {
+		Errors []ErrorDetail `json:"errors"`
+	}
+	if json.Unmarshal(body, &errResp) == nil {
+		e.Errors = errResp.Errors
+	}
+	return e
 }
 
 // Client is the interface containing the methods for connecting to the Aura API.
 type Client interface {
+	ListInstances(tenantID string) (*ListResponse, error)
+	ListInstancesWithContext(ctx context.Context, tenantID string) (*ListResponse, error)
 	CreateInstance(name, cloudProvider, memory, version, region, instanceType string) (*CreateResponse, error)
+	CreateInstanceWithContext(
+		ctx context.Context, name, cloudProvider, memory, version, region, instanceType string,
+	) (*CreateResponse, error)
+	CreateInstanceFromRequest(r CreateInstanceRequest) (*CreateResponse, error)
+	CreateInstanceFromRequestWithContext(ctx context.Context, r CreateInstanceRequest) (*CreateResponse, error)
 	GetInstance(id string) (*GetResponse, error)
+	GetInstanceWithContext(ctx context.Context, id string) (*GetResponse, error)
 	DestroyInstance(id string) error
+	DestroyInstanceWithContext(ctx context.Context, id string) error
 	PauseInstance(id string) error
+	PauseInstanceWithContext(ctx context.Context, id string) error
+	ResumeInstance(id string) error
+	ResumeInstanceWithContext(ctx context.Context, id string) error
+	UpdateInstance(id string, update UpdateRequest) (*GetResponse, error)
+	UpdateInstanceWithContext(ctx context.Context, id string, update UpdateRequest) (*GetResponse, error)
+	OverwriteInstance(targetID, sourceInstanceID, sourceSnapshotID string) (*GetResponse, error)
+	OverwriteInstanceWithContext(
+		ctx context.Context, targetID, sourceInstanceID, sourceSnapshotID string,
+	) (*GetResponse, error)
+	ListSnapshots(instanceID, date string) (*ListSnapshotsResponse, error)
+	ListSnapshotsWithContext(ctx context.Context, instanceID, date string) (*ListSnapshotsResponse, error)
+	CreateSnapshot(instanceID string) (*CreateSnapshotResponse, error)
+	CreateSnapshotWithContext(ctx context.Context, instanceID string) (*CreateSnapshotResponse, error)
+	GetSnapshot(instanceID, snapshotID string) (*GetSnapshotResponse, error)
+	GetSnapshotWithContext(ctx context.Context, instanceID, snapshotID string) (*GetSnapshotResponse, error)
+	RestoreSnapshot(instanceID, snapshotID string) (*GetResponse, error)
+	RestoreSnapshotWithContext(ctx context.Context, instanceID, snapshotID string) (*GetResponse, error)
+	ListTenants() (*ListTenantsResponse, error)
+	ListTenantsWithContext(ctx context.Context) (*ListTenantsResponse, error)
+	GetTenant(id string) (*TenantResponse, error)
+	GetTenantWithContext(ctx context.Context, id string) (*TenantResponse, error)
+	WaitForStatus(ctx context.Context, id string, targetStatuses []string, options ...WaitOption) (*GetResponse, error)
+	ListCustomerManagedKeys(tenantID string) (*ListCustomerManagedKeysResponse, error)
+	ListCustomerManagedKeysWithContext(ctx context.Context, tenantID string) (*ListCustomerManagedKeysResponse, error)
+	CreateCustomerManagedKey(r CreateCustomerManagedKeyRequest) (*CustomerManagedKeyResponse, error)
+	CreateCustomerManagedKeyWithContext(
+		ctx context.Context, r CreateCustomerManagedKeyRequest,
+	) (*CustomerManagedKeyResponse, error)
+	GetCustomerManagedKey(id string) (*CustomerManagedKeyResponse, error)
+	GetCustomerManagedKeyWithContext(ctx context.Context, id string) (*CustomerManagedKeyResponse, error)
+	DeleteCustomerManagedKey(id string) error
+	DeleteCustomerManagedKeyWithContext(ctx context.Context, id string) error
+	WaitForCustomerManagedKey(ctx context.Context, id string, options ...WaitOption) (*CustomerManagedKeyResponse, error)
+	EstimateSessionSize(r SessionSizeRequest) (*SessionSizeResponse, error)
+	EstimateSessionSizeWithContext(ctx context.Context, r SessionSizeRequest) (*SessionSizeResponse, error)
+	ListSessions(tenantID, instanceID string) (*ListSessionsResponse, error)
+	ListSessionsWithContext(ctx context.Context, tenantID, instanceID string) (*ListSessionsResponse, error)
+	CreateSession(r CreateSessionRequest) (*SessionResponse, error)
+	CreateSessionWithContext(ctx context.Context, r CreateSessionRequest) (*SessionResponse, error)
+	GetSession(id string) (*SessionResponse, error)
+	GetSessionWithContext(ctx context.Context, id string) (*SessionResponse, error)
+	DeleteSession(id string) error
+	DeleteSessionWithContext(ctx context.Context, id string) error
+	WaitForSession(ctx context.Context, id string, options ...WaitOption) (*SessionResponse, error)
+	GetMetricsIntegration(tenantID string) (*MetricsIntegrationResponse, error)
+	GetMetricsIntegrationWithContext(ctx context.Context, tenantID string) (*MetricsIntegrationResponse, error)
+	ScrapeMetrics(endpoint string) (*Metrics, error)
+	ScrapeMetricsWithContext(ctx context.Context, endpoint string) (*Metrics, error)
 }
 
 type client struct {
-	httpClient *http.Client
-	logger     *slog.Logger
-	endpoint   string
-	tenantID   string
-	retries    int
-	version    string
+	httpClient       *http.Client
+	logger           *slog.Logger
+	endpoint         string
+	tenantID         string
+	retries          int
+	version          string
+	validateConfig   bool
+	configMu         sync.Mutex
+	configurations   map[string][]InstanceConfiguration // Instance configurations by tenant ID
+	rateLimiter      *rateLimiter
+	rateLimitRetries int
+	maxRetryAfter    time.Duration
+	retryPolicy      RetryPolicy
+	idempotentCreate bool
+	telemetry        *telemetry
+	tokenSource      TokenSource
+	tokenCacheFile   string
+	tokenCacheOpts   []FileTokenOption
 }
 
-type option func(*client)
+// Option customizes the client returned by NewClient.
+type Option func(*client)
 
 // NewClient creates a new client based on a given client ID and secret as well as
 // options for customizing the returned client.
-func NewClient(ctx context.Context, clientID, clientSecret, tenantID string, options ...option) (*client, error) {
+// The context is not used, and is only kept so existing callers keep compiling.
+// Tokens are fetched using the context of the request needing them, so use the
+// ...WithContext methods for controlling cancellation and deadlines.
+func NewClient(_ context.Context, clientID, clientSecret, tenantID string, options ...Option) (*client, error) {
 	c := &client{
-		logger:   slog.Default(),
-		endpoint: endpoint,
-		retries:  retries,
-		tenantID: tenantID,
-		version:  version,
+		logger:           slog.Default(),
+		endpoint:         endpoint,
+		retries:          retries,
+		tenantID:         tenantID,
+		version:          version,
+		rateLimiter:      &rateLimiter{},
+		rateLimitRetries: rateLimitRetries,
+		maxRetryAfter:    defaultMaxRetryAfter,
+		telemetry:        newTelemetry(),
 	}
 	for _, o := range options {
 		o(c)
 	}
-	r := retryablehttp.NewClient()
-	r.RetryMax = c.retries
+	if err := c.telemetry.init(); err != nil {
+		return nil, err
+	}
+	r := c.newRetryClient()
 	r.ErrorHandler = func(resp *http.Response, err error, numTries int) (*http.Response, error) {
+		// Without a response there is nothing for Aura support to trace, so
+		// keep the cause, i.e. a cancelled context, inspectable instead.
+		if resp == nil {
+			return nil, fmt.Errorf("gave up after %d attempts: %w", numTries, err)
+		}
 		var m string
 		if err != nil {
 			m += fmt.Sprintln(err.Error())
 		}
-		if resp != nil {
-			m += fmt.Sprintln(resp.Status)
-		}
+		m += fmt.Sprintln(resp.Status)
 		e := errors.New(m + fmt.Sprintf(" Gave up after %d attempts", numTries))
 		return resp, newAuraError(e, resp)
 	}
 	if c.httpClient == nil {
 		c.httpClient = r.StandardClient()
 	}
-	conf := &clientcredentials.Config{
-		ClientID:     clientID,
-		ClientSecret: clientSecret,
-		TokenURL:     c.endpoint + "/oauth/token",
+	if c.tokenSource == nil {
+		c.tokenSource = &clientCredentials{
+			conf: &clientcredentials.Config{
+				ClientID:     clientID,
+				ClientSecret: clientSecret,
+				TokenURL:     c.endpoint + "/oauth/token",
+			},
+			base: c.httpClient,
+		}
+	}
+	if c.tokenCacheFile != "" {
+		c.tokenSource = NewFileTokenSource(c.tokenCacheFile, c.tokenSource, c.tokenCacheOpts...)
 	}
-	ctx = context.WithValue(ctx, oauth2.HTTPClient, c.httpClient)
-	c.httpClient = conf.Client(ctx)
+	c.httpClient = newTokenClient(c.tokenSource, c.httpClient)
 	return c, nil
 }
 
 // WithHTTPClient sets the HTTP client used to communicate with Aura.
-func WithHTTPClient(h *http.Client) option {
+func WithHTTPClient(h *http.Client) Option {
 	return func(c *client) {
 		c.httpClient = h
 	}
 }
 
 // WithEndpoint sets the a custom endpoint for Aura.
-func WithEndpoint(e string) option {
+func WithEndpoint(e string) Option {
 	return func(c *client) {
 		c.endpoint = e
 	}
@@ -117,27 +269,68 @@ func WithEndpoint(e string) option {
 
 // WithRetries sets the maximum number of retries for requests. By default
 // we do not retry and the maximum number of retries are 3.
-// Requests are retried with exp backoff on 5xx errors as recommended by Neo4J.
-func WithRetries(n int) option {
+// Requests are retried with exp backoff on 5xx errors as recommended by Neo4J,
+// see DefaultRetryPolicy. Use WithRetryPolicy for more control.
+func WithRetries(n int) Option {
 	return func(c *client) {
 		c.retries = n
 	}
 }
 
 // WithLogger sets a custom logger instead instead of slog
-func WithLogger(l *slog.Logger) option {
+func WithLogger(l *slog.Logger) Option {
 	return func(c *client) {
 		c.logger = l
 	}
 }
 
 // WithVersion sets the client to use a given API version
-func WithVersion(v string) option {
+func WithVersion(v string) Option {
 	return func(c *client) {
 		c.version = v
 	}
 }
 
+// CloudProvider is a cloud provider Aura can host instances at.
+type CloudProvider string
+
+const (
+	CloudProviderGCP   CloudProvider = "gcp"
+	CloudProviderAWS   CloudProvider = "aws"
+	CloudProviderAzure CloudProvider = "azure"
+)
+
+// InstanceType is the kind of instance to create in Aura.
+type InstanceType string
+
+const (
+	InstanceTypeFreeDB           InstanceType = "free-db"
+	InstanceTypeProfessionalDB   InstanceType = "professional-db"
+	InstanceTypeProfessionalDS   InstanceType = "professional-ds"
+	InstanceTypeEnterpriseDB     InstanceType = "enterprise-db"
+	InstanceTypeEnterpriseDS     InstanceType = "enterprise-ds"
+	InstanceTypeBusinessCritical InstanceType = "business-critical"
+)
+
+// CreateInstanceRequest describes a new instance and is constructed from the values from
+// https://neo4j.com/docs/aura/platform/api/specification/#/instances/post-instances.
+// Optional fields left empty are not sent to Aura.
+type CreateInstanceRequest struct {
+	Name                 string        `json:"name"`                              // The name of the instance
+	TenantID             string        `json:"tenant_id"`                         // Defaults to the tenant of the client
+	CloudProvider        CloudProvider `json:"cloud_provider"`                    // GCP, AWS, ...
+	Region               string        `json:"region"`                            // us-east1, eu-central2, ...
+	InstanceType         InstanceType  `json:"type"`                              // enterprise-db, professional-db, ...
+	Memory               string        `json:"memory"`                            // Amount of memory, i.e. "8GB"
+	Version              string        `json:"version"`                           // Neo4j version, i.e. "5"
+	Storage              string        `json:"storage,omitempty"`                 // Amount of storage, i.e. "16GB"
+	CustomerManagedKeyID string        `json:"customer_managed_key_id,omitempty"` // Key to encrypt the instance with
+	GraphAnalyticsPlugin bool          `json:"graph_analytics_plugin,omitempty"`  // Enable the graph analytics plugin
+	VectorOptimized      bool          `json:"vector_optimized,omitempty"`        // Optimize for vector search
+	SourceInstanceID     string        `json:"source_instance_id,omitempty"`      // Clone the data of this instance
+	SourceSnapshotID     string        `json:"source_snapshot_id,omitempty"`      // Clone the data of this snapshot
+}
+
 type ResponseCommonProperties struct {
 	ID            string `json:"id"`             // Internal ID of the instance
 	Name          string `json:"name"`           // The name we chose for the instance
@@ -148,6 +341,17 @@ type ResponseCommonProperties struct {
 	InstanceType  string `json:"type"`           // enterprise-db, professional-db, ...
 }
 
+type ListResponseData struct {
+	ResponseCommonProperties
+}
+
+// ListResponse is returned when listing Aura instances and is
+// constructed from the values from
+// https://neo4j.com/docs/aura/platform/api/specification/#/instances/get-instances.
+type ListResponse struct {
+	Data []ListResponseData `json:"data"`
+}
+
 type CreateResponseData struct {
 	ResponseCommonProperties
 	Username string `json:"username"` // Name of the initial admin user
@@ -161,11 +365,26 @@ type CreateResponse struct {
 	Data CreateResponseData `json:"data"`
 }
 
+// Statuses reported by the Aura API for an instance.
+const (
+	StatusCreating    = "creating"
+	StatusRunning     = "running"
+	StatusPausing     = "pausing"
+	StatusPaused      = "paused"
+	StatusResuming    = "resuming"
+	StatusDestroying  = "destroying"
+	StatusUpdating    = "updating"
+	StatusRestoring   = "restoring"
+	StatusOverwriting = "overwriting"
+)
+
 type GetResponseData struct {
 	ResponseCommonProperties
-	Status  string `json:"status"`  // Indicates whether the instance is ready or under setup
-	Memory  string `json:"memory"`  // Amount of memory allocated, i.e. "8GB"
-	Storage string `json:"storage"` // Amount of storage allocated, i.e. "16GB"
+	Status                string `json:"status"`                  // Indicates whether the instance is ready or under setup
+	Memory                string `json:"memory"`                  // Amount of memory allocated, i.e. "8GB"
+	Storage               string `json:"storage"`                 // Amount of storage allocated, i.e. "16GB"
+	CustomerManagedKeyID  string `json:"customer_managed_key_id"` // Key the instance is encrypted with, if any
+	MetricsIntegrationURL string `json:"metrics_integration_url"` // Prometheus endpoint with the metrics of the instance
 }
 
 // GetResponse contains information about a given Aura instance and
@@ -175,20 +394,124 @@ type GetResponse struct {
 	Data GetResponseData `json:"data"`
 }
 
+// ListInstances returns the instances the client has access to. If a tenant ID
+// is given only instances belonging to that tenant are returned.
+// To specify the context, use ListInstancesWithContext.
+func (c *client) ListInstances(tenantID string) (*ListResponse, error) {
+	return c.ListInstancesWithContext(context.Background(), tenantID)
+}
+
+// ListInstancesWithContext is like ListInstances but uses the given context for the request.
+func (c *client) ListInstancesWithContext(ctx context.Context, tenantID string) (_ *ListResponse, err error) {
+	ctx, op := c.startOperation(ctx, "ListInstances", AttributeTenantID.String(tenantID))
+	defer op.end(&err)
+	path := c.api() + "/instances"
+	if tenantID != "" {
+		path += "?" + url.Values{"tenantId": {tenantID}}.Encode()
+	}
+	req, err := c.newRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, newAuraError(errors.New(resp.Status), resp)
+	}
+
+	var listResp ListResponse
+	err = json.NewDecoder(resp.Body).Decode(&listResp)
+	if err != nil {
+		return nil, newAuraError(err, resp)
+	}
+
+	return &listResp, nil
+}
+
+// UpdateRequest holds the changes to apply to an instance. Fields left empty
+// are not sent to the API and are therefore left unchanged.
+type UpdateRequest struct {
+	Name   string // New name of the instance
+	Memory string // New amount of memory, i.e. "16GB"
+}
+
 // CreateInstance attempts to create a new Aura instance with the given name
 // returning information about the instance if successful and otherwise
 // returning an error.
 // Possible values for the parameters can be found in the documentation of the Neo4J Aura API.
+// It is a shorthand for CreateInstanceFromRequest which supports all options for new instances.
+// To specify the context, use CreateInstanceWithContext.
 func (c *client) CreateInstance(name, cloudProvider, memory, version, region, instanceType string) (*CreateResponse, error) {
-	req, err := c.newRequest("POST", c.api()+"/instances", map[string]any{
-		"name":           name,
-		"tenant_id":      c.tenantID,
-		"cloud_provider": cloudProvider,
-		"type":           instanceType,
-		"memory":         memory,
-		"version":        version,
-		"region":         region,
+	return c.CreateInstanceWithContext(context.Background(), name, cloudProvider, memory, version, region, instanceType)
+}
+
+// CreateInstanceWithContext is like CreateInstance but uses the given context for the request.
+func (c *client) CreateInstanceWithContext(
+	ctx context.Context, name, cloudProvider, memory, version, region, instanceType string,
+) (*CreateResponse, error) {
+	return c.CreateInstanceFromRequestWithContext(ctx, CreateInstanceRequest{
+		Name:          name,
+		CloudProvider: CloudProvider(cloudProvider),
+		Memory:        memory,
+		Version:       version,
+		Region:        region,
+		InstanceType:  InstanceType(instanceType),
 	})
+}
+
+// CreateInstanceFromRequest attempts to create a new Aura instance as described by the
+// request, returning information about the instance if successful and otherwise
+// returning an error.
+// If the client was created using WithConfigValidation the request is checked against
+// the configurations allowed for the tenant before the instance is created.
+// If the client was created using WithIdempotentCreate an existing instance with the
+// same name is returned instead of creating another one.
+// To specify the context, use CreateInstanceFromRequestWithContext.
+func (c *client) CreateInstanceFromRequest(r CreateInstanceRequest) (*CreateResponse, error) {
+	return c.CreateInstanceFromRequestWithContext(context.Background(), r)
+}
+
+// CreateInstanceFromRequestWithContext is like CreateInstanceFromRequest but uses the given
+// context for the request.
+func (c *client) CreateInstanceFromRequestWithContext(
+	ctx context.Context, r CreateInstanceRequest,
+) (_ *CreateResponse, err error) {
+	if r.TenantID == "" {
+		r.TenantID = c.tenantID
+	}
+	ctx, op := c.startOperation(ctx, "CreateInstance", AttributeTenantID.String(r.TenantID))
+	defer op.end(&err)
+	if c.validateConfig {
+		err := c.validateConfiguration(ctx, r.TenantID, InstanceConfiguration{
+			CloudProvider: string(r.CloudProvider),
+			Region:        r.Region,
+			Memory:        r.Memory,
+			InstanceType:  string(r.InstanceType),
+			Version:       r.Version,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	var resp *CreateResponse
+	if c.idempotentCreate {
+		resp, err = c.createInstanceOnce(ctx, r)
+	} else {
+		resp, err = c.createInstance(ctx, r)
+	}
+	if err != nil {
+		return nil, err
+	}
+	op.span.SetAttributes(AttributeInstanceID.String(resp.Data.ID))
+	return resp, nil
+}
+
+func (c *client) createInstance(ctx context.Context, r CreateInstanceRequest) (*CreateResponse, error) {
+	req, err := c.newRequest(ctx, "POST", c.api()+"/instances", r)
 	if err != nil {
 		return nil, err
 	}
@@ -213,8 +536,16 @@ func (c *client) CreateInstance(name, cloudProvider, memory, version, region, in
 
 // GetInstance attempts to get information about an instance identified
 // by the ID assigned to it by Neo4J.
+// To specify the context, use GetInstanceWithContext.
 func (c *client) GetInstance(id string) (*GetResponse, error) {
-	req, err := c.newRequest("GET", c.api()+"/instances/"+id, nil)
+	return c.GetInstanceWithContext(context.Background(), id)
+}
+
+// GetInstanceWithContext is like GetInstance but uses the given context for the request.
+func (c *client) GetInstanceWithContext(ctx context.Context, id string) (_ *GetResponse, err error) {
+	ctx, op := c.startOperation(ctx, "GetInstance", AttributeInstanceID.String(id))
+	defer op.end(&err)
+	req, err := c.newRequest(ctx, "GET", c.api()+"/instances/"+id, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -237,11 +568,144 @@ func (c *client) GetInstance(id string) (*GetResponse, error) {
 	return &getResp, nil
 }
 
+// UpdateInstance renames and/or resizes the memory of an instance. Only the
+// fields set in the update are changed.
+// Resizing is asynchronous, so the returned instance will have the status
+// "updating" until Aura has finished applying the new memory size.
+// To specify the context, use UpdateInstanceWithContext.
+func (c *client) UpdateInstance(id string, update UpdateRequest) (*GetResponse, error) {
+	return c.UpdateInstanceWithContext(context.Background(), id, update)
+}
+
+// UpdateInstanceWithContext is like UpdateInstance but uses the given context for the request.
+func (c *client) UpdateInstanceWithContext(
+	ctx context.Context, id string, update UpdateRequest,
+) (_ *GetResponse, err error) {
+	ctx, op := c.startOperation(ctx, "UpdateInstance", AttributeInstanceID.String(id))
+	defer op.end(&err)
+	reqBody := map[string]any{}
+	if update.Name != "" {
+		reqBody["name"] = update.Name
+	}
+	if update.Memory != "" {
+		reqBody["memory"] = update.Memory
+	}
+	if len(reqBody) == 0 {
+		return nil, errors.New("no changes given for updating instance " + id)
+	}
+	req, err := c.newRequest(ctx, "PATCH", c.api()+"/instances/"+id, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, newAuraError(errors.New(resp.Status), resp)
+	}
+
+	var updateResp GetResponse
+	err = json.NewDecoder(resp.Body).Decode(&updateResp)
+	if err != nil {
+		return nil, newAuraError(err, resp)
+	}
+
+	return &updateResp, nil
+}
+
+// OverwriteInstance replaces the data of the target instance with the data of
+// either another instance or a snapshot. Exactly one of sourceInstanceID and
+// sourceSnapshotID must be given.
+// Overwriting is asynchronous, so the returned instance will have the status
+// "overwriting" until Aura is done.
+// To specify the context, use OverwriteInstanceWithContext.
+func (c *client) OverwriteInstance(targetID, sourceInstanceID, sourceSnapshotID string) (*GetResponse, error) {
+	return c.OverwriteInstanceWithContext(context.Background(), targetID, sourceInstanceID, sourceSnapshotID)
+}
+
+// OverwriteInstanceWithContext is like OverwriteInstance but uses the given context for the request.
+func (c *client) OverwriteInstanceWithContext(
+	ctx context.Context, targetID, sourceInstanceID, sourceSnapshotID string,
+) (_ *GetResponse, err error) {
+	ctx, op := c.startOperation(ctx, "OverwriteInstance", AttributeInstanceID.String(targetID))
+	defer op.end(&err)
+	reqBody := map[string]any{}
+	switch {
+	case sourceInstanceID != "" && sourceSnapshotID != "":
+		return nil, errors.New("only one of source instance and source snapshot can be given when overwriting")
+	case sourceInstanceID != "":
+		reqBody["source_instance_id"] = sourceInstanceID
+	case sourceSnapshotID != "":
+		reqBody["source_snapshot_id"] = sourceSnapshotID
+	default:
+		return nil, errors.New("a source instance or source snapshot is needed for overwriting")
+	}
+	req, err := c.newRequest(ctx, "POST", c.api()+"/instances/"+targetID+"/overwrite", reqBody)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, newAuraError(errors.New(resp.Status), resp)
+	}
+
+	var overwriteResp GetResponse
+	err = json.NewDecoder(resp.Body).Decode(&overwriteResp)
+	if err != nil {
+		return nil, newAuraError(err, resp)
+	}
+
+	return &overwriteResp, nil
+}
+
 // PauseInstance puts a given instance on pause, making it unavailable for use.
 // Note that you can only put instances on pause for a certain amount of time after which
 // they automatically be put online again. Check the Aura documentation for details.
+// To specify the context, use PauseInstanceWithContext.
 func (c *client) PauseInstance(id string) error {
-	req, err := c.newRequest("POST", c.api()+"/instances/"+id+"/pause", nil)
+	return c.PauseInstanceWithContext(context.Background(), id)
+}
+
+// PauseInstanceWithContext is like PauseInstance but uses the given context for the request.
+func (c *client) PauseInstanceWithContext(ctx context.Context, id string) (err error) {
+	ctx, op := c.startOperation(ctx, "PauseInstance", AttributeInstanceID.String(id))
+	defer op.end(&err)
+	req, err := c.newRequest(ctx, "POST", c.api()+"/instances/"+id+"/pause", nil)
+	if err != nil {
+		return err
+	}
+	apiResp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer apiResp.Body.Close()
+	if apiResp.StatusCode >= http.StatusOK && apiResp.StatusCode < http.StatusMultipleChoices {
+		return nil
+	}
+	return newAuraError(errors.New(apiResp.Status), apiResp)
+}
+
+// ResumeInstance brings a paused instance back online.
+// Resuming an instance that is already running is seen as successful, so the
+// operation can safely be repeated.
+// To specify the context, use ResumeInstanceWithContext.
+func (c *client) ResumeInstance(id string) error {
+	return c.ResumeInstanceWithContext(context.Background(), id)
+}
+
+// ResumeInstanceWithContext is like ResumeInstance but uses the given context for the request.
+func (c *client) ResumeInstanceWithContext(ctx context.Context, id string) (err error) {
+	ctx, op := c.startOperation(ctx, "ResumeInstance", AttributeInstanceID.String(id))
+	defer op.end(&err)
+	req, err := c.newRequest(ctx, "POST", c.api()+"/instances/"+id+"/resume", nil)
 	if err != nil {
 		return err
 	}
@@ -249,16 +713,33 @@ func (c *client) PauseInstance(id string) error {
 	if err != nil {
 		return err
 	}
+	defer apiResp.Body.Close()
 	if apiResp.StatusCode >= http.StatusOK && apiResp.StatusCode < http.StatusMultipleChoices {
 		return nil
 	}
+	// Aura refuses to resume instances that are not paused, so check
+	// whether the instance is already where we want it to be.
+	if apiResp.StatusCode == http.StatusBadRequest || apiResp.StatusCode == http.StatusConflict {
+		getResp, getErr := c.GetInstanceWithContext(ctx, id)
+		if getErr == nil && getResp.Data.Status == StatusRunning {
+			return nil
+		}
+	}
 	return newAuraError(errors.New(apiResp.Status), apiResp)
 }
 
 // Destroy instance tears down an instance identified by the Aura ID
 // A 404 from the API is seen as successful as it indicates the instance no longer exists
+// To specify the context, use DestroyInstanceWithContext.
 func (c *client) DestroyInstance(id string) error {
-	req, err := c.newRequest("DELETE", c.api()+"/instances/"+id, nil)
+	return c.DestroyInstanceWithContext(context.Background(), id)
+}
+
+// DestroyInstanceWithContext is like DestroyInstance but uses the given context for the request.
+func (c *client) DestroyInstanceWithContext(ctx context.Context, id string) (err error) {
+	ctx, op := c.startOperation(ctx, "DestroyInstance", AttributeInstanceID.String(id))
+	defer op.end(&err)
+	req, err := c.newRequest(ctx, "DELETE", c.api()+"/instances/"+id, nil)
 	if err != nil {
 		return err
 	}
@@ -266,6 +747,7 @@ func (c *client) DestroyInstance(id string) error {
 	if err != nil {
 		return err
 	}
+	defer apiResp.Body.Close()
 	if apiResp.StatusCode == http.StatusNotFound ||
 		(apiResp.StatusCode >= http.StatusOK && apiResp.StatusCode < http.StatusMultipleChoices) {
 		return nil
@@ -275,8 +757,9 @@ func (c *client) DestroyInstance(id string) error {
 
 // newRequest returns a request that is valid for the Neo4J Aura API
 // given the HTTP method and path as well as a potential request body to
-// add as a payload.
-func (c *client) newRequest(method, path string, reqBody map[string]any) (*http.Request, error) {
+// add as a payload. Cancelling the context aborts the request, including
+// any retries and fetching of tokens.
+func (c *client) newRequest(ctx context.Context, method, path string, reqBody any) (*http.Request, error) {
 	var body []byte
 	var err error
 	// Parse and add body
@@ -286,7 +769,7 @@ func (c *client) newRequest(method, path string, reqBody map[string]any) (*http.
 			return nil, err
 		}
 	}
-	req, err := http.NewRequest(method, path, bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, method, path, bytes.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
@@ -294,15 +777,16 @@ func (c *client) newRequest(method, path string, reqBody map[string]any) (*http.
 	// Inject headers
 	req.Header.Add("Content-Type", "application/json")
 	req.Header.Add("Accept", "application/json")
-	return req, nil
+	return withRequest(req), nil
 }
 
 func (c *client) do(req *http.Request) (*http.Response, error) {
 	// Perform the call
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.doRateLimited(req)
 	if err != nil {
 		return nil, err
 	}
+	recordResponse(req.Context(), resp)
 	// Issue a warning if the endpoint is marked for deprecation
 	if dep := resp.Header.Get("X-Tyk-Api-Expires"); dep != "" {
 		c.logger.Warn(c.version + " of the Neo4J Aura API expires on " + dep + ".\nEncountered at " + req.URL.String())
@@ -313,15 +797,3 @@ func (c *client) do(req *http.Request) (*http.Response, error) {
 func (c *client) api() string {
 	return c.endpoint + "/" + c.version
 }
-
-func responseBodyToString(resp *http.Response) string {
-	if resp == nil {
-		return ""
-	}
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
-	if err != nil || len(body) == 0 {
-		return ""
-	}
-	return string(body)
-}
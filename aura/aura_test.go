@@ -4,14 +4,31 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
+	"math"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
 	"regexp"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/indykite/aura-api-client/aura"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"golang.org/x/oauth2"
 )
 
 const responseId = "track-me-123"
@@ -24,10 +41,58 @@ const (
 	GET_INSTANCE
 	PAUSE_INSTANCE
 	AUTHENTICATE
+	LIST_INSTANCES
+	RESUME_INSTANCE
+	UPDATE_INSTANCE
+	LIST_SNAPSHOTS
+	CREATE_SNAPSHOT
+	GET_SNAPSHOT
+	RESTORE_SNAPSHOT
+	OVERWRITE_INSTANCE
+	LIST_TENANTS
+	GET_TENANT
+	LIST_KEYS
+	CREATE_KEY
+	GET_KEY
+	DELETE_KEY
+	SIZE_SESSION
+	LIST_SESSIONS
+	CREATE_SESSION
+	GET_SESSION
+	DELETE_SESSION
+	METRICS_INTEGRATION
+	SCRAPE_METRICS
 )
 
 var callCounter map[Path]int
 
+// handlerMu guards responseMap and callCounter while the test server handles a
+// request, and inFlight tracks those requests, so the requests a spec cancelled
+// are done before the next spec replaces them.
+var (
+	handlerMu sync.Mutex
+	inFlight  sync.WaitGroup
+)
+
+// recordingPolicy is a RetryPolicy retrying everything without waiting,
+// recording the methods of the requests it was asked about.
+type recordingPolicy struct {
+	methods []string
+}
+
+func (p *recordingPolicy) MaxRetries() int {
+	return 1
+}
+
+func (p *recordingPolicy) ShouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	p.methods = append(p.methods, req.Method)
+	return true
+}
+
+func (p *recordingPolicy) Backoff(retry int, resp *http.Response) time.Duration {
+	return 0
+}
+
 type F func(w http.ResponseWriter, r *http.Request) error
 
 var responseMap map[Path]F
@@ -72,18 +137,98 @@ func mockError(code int) F {
 				},
 			},
 		}
-		_, err := json.Marshal(m)
+		b, err := json.Marshal(m)
+		if err != nil {
+			panic(err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Request-Id", responseId)
+		w.WriteHeader(code)
+		_, _ = w.Write(b)
+		return nil
+	}
+}
+
+func mockedSnapshot(instanceID, snapshotID string) map[string]any {
+	return map[string]any{
+		"instance_id": instanceID,
+		"snapshot_id": snapshotID,
+		"profile":     "AddHoc",
+		"status":      "Completed",
+		"timestamp":   "2024-01-31T12:00:00Z",
+	}
+}
+
+func mockedTenant(id string) map[string]any {
+	return map[string]any{
+		"id":   id,
+		"name": "Production",
+		"instance_configurations": []any{
+			map[string]any{
+				"cloud_provider": "gcp",
+				"region":         "europe-west1",
+				"region_name":    "Belgium (europe-west1)",
+				"memory":         "8GB",
+				"storage":        "16GB",
+				"type":           "enterprise-db",
+				"version":        "5",
+			},
+			map[string]any{
+				"cloud_provider": "gcp",
+				"region":         "europe-west1",
+				"region_name":    "Belgium (europe-west1)",
+				"memory":         "16GB",
+				"storage":        "32GB",
+				"type":           "enterprise-db",
+				"version":        "5",
+			},
+			map[string]any{
+				"cloud_provider": "aws",
+				"region":         "us-east-1",
+				"region_name":    "US East, N. Virginia (us-east-1)",
+				"memory":         "8GB",
+				"storage":        "16GB",
+				"type":           "enterprise-db",
+				"version":        "5",
+			},
+		},
+	}
+}
+
+func mockJSON(code int, m map[string]any) F {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		b, err := json.Marshal(m)
 		if err != nil {
 			panic(err)
 		}
 		w.Header().Set("Content-Type", "application/json")
 		w.Header().Set("X-Request-Id", responseId)
 		w.WriteHeader(code)
-		_, _ = w.Write([]byte(`500 not working`))
+		_, _ = w.Write(b)
 		return nil
 	}
 }
 
+// mockStatuses makes the instance report the given statuses in order,
+// repeating the last one once they have all been reported.
+func mockStatuses(id string, statuses ...string) {
+	i := 0
+	responseMap[GET_INSTANCE] = func(w http.ResponseWriter, r *http.Request) error {
+		status := statuses[min(i, len(statuses)-1)]
+		i++
+		return mockJSON(http.StatusOK, map[string]any{
+			"data": map[string]any{"id": id, "name": "Production", "status": status},
+		})(w, r)
+	}
+}
+
+// tokenSourceFunc is an aura.TokenSource calling the function.
+type tokenSourceFunc func(ctx context.Context) (*oauth2.Token, error)
+
+func (f tokenSourceFunc) Token(ctx context.Context) (*oauth2.Token, error) {
+	return f(ctx)
+}
+
 func mockGet(id string) {
 	f := func(w http.ResponseWriter, r *http.Request) error {
 		code, b := mockedGetResponse(id)
@@ -132,8 +277,97 @@ var _ = Describe("Aura", Ordered, func() {
 			panic(err)
 		}
 		routes[PAUSE_INSTANCE] = pat
+		pat, err = regexp.Compile(`^\/v1\/instances$`)
+		if err != nil {
+			panic(err)
+		}
+		routes[LIST_INSTANCES] = pat
+		pat, err = regexp.Compile(`^\/v1\/instances\/\w+\/resume$`)
+		if err != nil {
+			panic(err)
+		}
+		routes[RESUME_INSTANCE] = pat
+		pat, err = regexp.Compile(`^\/v1\/instances\/\w+$`)
+		if err != nil {
+			panic(err)
+		}
+		routes[UPDATE_INSTANCE] = pat
+		pat, err = regexp.Compile(`^\/v1\/instances\/\w+\/snapshots$`)
+		if err != nil {
+			panic(err)
+		}
+		routes[LIST_SNAPSHOTS] = pat
+		routes[CREATE_SNAPSHOT] = pat
+		pat, err = regexp.Compile(`^\/v1\/instances\/\w+\/snapshots\/[\w-]+$`)
+		if err != nil {
+			panic(err)
+		}
+		routes[GET_SNAPSHOT] = pat
+		pat, err = regexp.Compile(`^\/v1\/instances\/\w+\/snapshots\/[\w-]+\/restore$`)
+		if err != nil {
+			panic(err)
+		}
+		routes[RESTORE_SNAPSHOT] = pat
+		pat, err = regexp.Compile(`^\/v1\/instances\/\w+\/overwrite$`)
+		if err != nil {
+			panic(err)
+		}
+		routes[OVERWRITE_INSTANCE] = pat
+		pat, err = regexp.Compile(`^\/v1\/tenants$`)
+		if err != nil {
+			panic(err)
+		}
+		routes[LIST_TENANTS] = pat
+		pat, err = regexp.Compile(`^\/v1\/tenants\/[\w-]+$`)
+		if err != nil {
+			panic(err)
+		}
+		routes[GET_TENANT] = pat
+		pat, err = regexp.Compile(`^\/v1\/customer-managed-keys$`)
+		if err != nil {
+			panic(err)
+		}
+		routes[LIST_KEYS] = pat
+		routes[CREATE_KEY] = pat
+		pat, err = regexp.Compile(`^\/v1\/customer-managed-keys\/[\w-]+$`)
+		if err != nil {
+			panic(err)
+		}
+		routes[GET_KEY] = pat
+		routes[DELETE_KEY] = pat
+		pat, err = regexp.Compile(`^\/v1\/graph-analytics\/sessions\/sizing$`)
+		if err != nil {
+			panic(err)
+		}
+		routes[SIZE_SESSION] = pat
+		pat, err = regexp.Compile(`^\/v1\/graph-analytics\/sessions$`)
+		if err != nil {
+			panic(err)
+		}
+		routes[LIST_SESSIONS] = pat
+		routes[CREATE_SESSION] = pat
+		pat, err = regexp.Compile(`^\/v1\/graph-analytics\/sessions\/[\w-]+$`)
+		if err != nil {
+			panic(err)
+		}
+		routes[GET_SESSION] = pat
+		routes[DELETE_SESSION] = pat
+		pat, err = regexp.Compile(`^\/v1\/tenants\/[\w-]+\/metrics-integration$`)
+		if err != nil {
+			panic(err)
+		}
+		routes[METRICS_INTEGRATION] = pat
+		pat, err = regexp.Compile(`^\/api\/v1\/[\w-]+\/metrics$`)
+		if err != nil {
+			panic(err)
+		}
+		routes[SCRAPE_METRICS] = pat
 		// Create the server
 		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			inFlight.Add(1)
+			defer inFlight.Done()
+			handlerMu.Lock()
+			defer handlerMu.Unlock()
 			var path Path
 			switch {
 			case r.Method == "POST" && routes[AUTHENTICATE].Match([]byte(r.URL.Path)):
@@ -146,10 +380,52 @@ var _ = Describe("Aura", Ordered, func() {
 				path = DESTROY_INSTANCE
 			case r.Method == "POST" && routes[PAUSE_INSTANCE].Match([]byte(r.URL.Path)):
 				path = PAUSE_INSTANCE
+			case r.Method == "GET" && routes[LIST_INSTANCES].Match([]byte(r.URL.Path)):
+				path = LIST_INSTANCES
+			case r.Method == "POST" && routes[RESUME_INSTANCE].Match([]byte(r.URL.Path)):
+				path = RESUME_INSTANCE
+			case r.Method == "PATCH" && routes[UPDATE_INSTANCE].Match([]byte(r.URL.Path)):
+				path = UPDATE_INSTANCE
+			case r.Method == "GET" && routes[LIST_SNAPSHOTS].Match([]byte(r.URL.Path)):
+				path = LIST_SNAPSHOTS
+			case r.Method == "POST" && routes[CREATE_SNAPSHOT].Match([]byte(r.URL.Path)):
+				path = CREATE_SNAPSHOT
+			case r.Method == "GET" && routes[GET_SNAPSHOT].Match([]byte(r.URL.Path)):
+				path = GET_SNAPSHOT
+			case r.Method == "POST" && routes[RESTORE_SNAPSHOT].Match([]byte(r.URL.Path)):
+				path = RESTORE_SNAPSHOT
+			case r.Method == "POST" && routes[OVERWRITE_INSTANCE].Match([]byte(r.URL.Path)):
+				path = OVERWRITE_INSTANCE
+			case r.Method == "GET" && routes[LIST_TENANTS].Match([]byte(r.URL.Path)):
+				path = LIST_TENANTS
+			case r.Method == "GET" && routes[GET_TENANT].Match([]byte(r.URL.Path)):
+				path = GET_TENANT
+			case r.Method == "GET" && routes[LIST_KEYS].Match([]byte(r.URL.Path)):
+				path = LIST_KEYS
+			case r.Method == "POST" && routes[CREATE_KEY].Match([]byte(r.URL.Path)):
+				path = CREATE_KEY
+			case r.Method == "GET" && routes[GET_KEY].Match([]byte(r.URL.Path)):
+				path = GET_KEY
+			case r.Method == "DELETE" && routes[DELETE_KEY].Match([]byte(r.URL.Path)):
+				path = DELETE_KEY
+			case r.Method == "POST" && routes[SIZE_SESSION].Match([]byte(r.URL.Path)):
+				path = SIZE_SESSION
+			case r.Method == "GET" && routes[LIST_SESSIONS].Match([]byte(r.URL.Path)):
+				path = LIST_SESSIONS
+			case r.Method == "POST" && routes[CREATE_SESSION].Match([]byte(r.URL.Path)):
+				path = CREATE_SESSION
+			case r.Method == "GET" && routes[GET_SESSION].Match([]byte(r.URL.Path)):
+				path = GET_SESSION
+			case r.Method == "DELETE" && routes[DELETE_SESSION].Match([]byte(r.URL.Path)):
+				path = DELETE_SESSION
+			case r.Method == "GET" && routes[METRICS_INTEGRATION].Match([]byte(r.URL.Path)):
+				path = METRICS_INTEGRATION
+			case r.Method == "GET" && routes[SCRAPE_METRICS].Match([]byte(r.URL.Path)):
+				path = SCRAPE_METRICS
 			default:
 				panic("Unexpected request for testing")
 			}
-			err = responseMap[path](w, r)
+			err := responseMap[path](w, r)
 			if err != nil {
 				panic(err)
 			}
@@ -159,13 +435,20 @@ var _ = Describe("Aura", Ordered, func() {
 
 	})
 	BeforeEach(func() {
+		handlerMu.Lock()
 		responseMap = make(map[Path]F)
 		callCounter = make(map[Path]int)
+		responseMap[AUTHENTICATE] = authSuccess
+		handlerMu.Unlock()
 		client, err = aura.NewClient(context.Background(), "foo", "bar", "mox", aura.WithEndpoint(server.URL))
 		if err != nil {
 			panic(err)
 		}
-		responseMap[AUTHENTICATE] = authSuccess
+	})
+	AfterEach(func() {
+		// Requests cancelled while being sent may not have reached the handler yet
+		server.CloseClientConnections()
+		inFlight.Wait()
 	})
 	Describe("Deprecation warnings", func() {
 		var f F
@@ -222,6 +505,143 @@ var _ = Describe("Aura", Ordered, func() {
 			Expect(callCounter[AUTHENTICATE]).To(Equal(1))
 		})
 	})
+	Describe("Token sources", func() {
+		var path string
+		// countingSource hands out tokens named after the number of tokens handed out
+		type countingSource struct {
+			mu       sync.Mutex
+			calls    int
+			delay    time.Duration
+			lifetime time.Duration // An hour unless set
+		}
+		token := func(s *countingSource) aura.TokenSource {
+			return tokenSourceFunc(func(ctx context.Context) (*oauth2.Token, error) {
+				time.Sleep(s.delay)
+				s.mu.Lock()
+				defer s.mu.Unlock()
+				s.calls++
+				lifetime := s.lifetime
+				if lifetime == 0 {
+					lifetime = time.Hour
+				}
+				return &oauth2.Token{
+					AccessToken: fmt.Sprintf("token-%d", s.calls),
+					TokenType:   "Bearer",
+					Expiry:      time.Now().Add(lifetime),
+				}, nil
+			})
+		}
+		BeforeEach(func() {
+			path = filepath.Join(GinkgoT().TempDir(), "aura", "token.json")
+		})
+		It("should be used instead of the client credentials", func() {
+			source := &countingSource{}
+			client, err := aura.NewClient(context.Background(), "foo", "bar", "mox",
+				aura.WithEndpoint(server.URL), aura.WithTokenSource(token(source)))
+			Expect(err).To(Succeed())
+			responseMap[GET_INSTANCE] = func(w http.ResponseWriter, r *http.Request) error {
+				Expect(r.Header.Get("Authorization")).To(Equal("Bearer token-1"))
+				code, b := mockedGetResponse("123id")
+				w.WriteHeader(code)
+				_, err := w.Write(b)
+				return err
+			}
+			_, err = client.GetInstance("123id")
+			Expect(err).To(Succeed())
+			_, err = client.GetInstance("123id")
+			Expect(err).To(Succeed())
+			Expect(callCounter[AUTHENTICATE]).To(Equal(0))
+			Expect(source.calls).To(Equal(1))
+		})
+		It("should share a token between clients using the same file", func() {
+			mockGet("123id")
+			for i := 0; i < 3; i++ {
+				client, err := aura.NewClient(context.Background(), "foo", "bar", "mox",
+					aura.WithEndpoint(server.URL), aura.WithTokenCacheFile(path))
+				Expect(err).To(Succeed())
+				_, err = client.GetInstance("123id")
+				Expect(err).To(Succeed())
+			}
+			Expect(callCounter[AUTHENTICATE]).To(Equal(1))
+			info, err := os.Stat(path)
+			Expect(err).To(Succeed())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0o600)))
+			b, err := os.ReadFile(path)
+			Expect(err).To(Succeed())
+			Expect(string(b)).To(ContainSubstring(`"access_token":"bar"`))
+		})
+		It("should replace tokens ahead of expiry", func() {
+			source := &countingSource{}
+			cache := aura.NewFileTokenSource(path, token(source), aura.WithRefreshAhead(time.Minute))
+			t, err := cache.Token(context.Background())
+			Expect(err).To(Succeed())
+			Expect(t.AccessToken).To(Equal("token-1"))
+			Expect(t.Expiry).To(BeTemporally("~", time.Now().Add(59*time.Minute), time.Second))
+
+			// The token in the file is about to expire
+			b, err := json.Marshal(map[string]any{
+				"access_token": "token-1", "token_type": "Bearer", "expiry": time.Now().Add(30 * time.Second),
+			})
+			Expect(err).To(Succeed())
+			Expect(os.WriteFile(path, b, 0o600)).To(Succeed())
+			t, err = aura.NewFileTokenSource(path, token(source), aura.WithRefreshAhead(time.Minute)).
+				Token(context.Background())
+			Expect(err).To(Succeed())
+			Expect(t.AccessToken).To(Equal("token-2"))
+		})
+		It("should share tokens living shorter than the refresh window", func() {
+			source := &countingSource{lifetime: 2 * time.Minute}
+			for i := 0; i < 3; i++ {
+				t, err := aura.NewFileTokenSource(path, token(source)).Token(context.Background())
+				Expect(err).To(Succeed())
+				Expect(t.AccessToken).To(Equal("token-1"))
+				// Replaced halfway through its lifetime rather than 5 minutes before it expires
+				Expect(t.Expiry).To(BeTemporally("~", time.Now().Add(time.Minute), time.Second))
+			}
+			Expect(source.calls).To(Equal(1))
+		})
+		It("should only get one token when several processes need one", func() {
+			source := &countingSource{delay: 50 * time.Millisecond}
+			var wg sync.WaitGroup
+			tokens := make([]string, 5)
+			for i := range tokens {
+				wg.Add(1)
+				go func(i int) {
+					defer GinkgoRecover()
+					defer wg.Done()
+					// A source of its own, like in another process
+					t, err := aura.NewFileTokenSource(path, token(source)).Token(context.Background())
+					Expect(err).To(Succeed())
+					tokens[i] = t.AccessToken
+				}(i)
+			}
+			wg.Wait()
+			Expect(tokens).To(HaveEach("token-1"))
+			Expect(source.calls).To(Equal(1))
+		})
+		It("should remove locks left behind", func() {
+			Expect(os.MkdirAll(filepath.Dir(path), 0o700)).To(Succeed())
+			Expect(os.WriteFile(path+".lock", nil, 0o600)).To(Succeed())
+			old := time.Now().Add(-time.Hour)
+			Expect(os.Chtimes(path+".lock", old, old)).To(Succeed())
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			t, err := aura.NewFileTokenSource(path, token(&countingSource{})).Token(ctx)
+			Expect(err).To(Succeed())
+			Expect(t.AccessToken).To(Equal("token-1"))
+			Expect(path + ".lock").NotTo(BeAnExistingFile())
+		})
+		It("should give up waiting for a lock when the context is done", func() {
+			Expect(os.MkdirAll(filepath.Dir(path), 0o700)).To(Succeed())
+			Expect(os.WriteFile(path+".lock", nil, 0o600)).To(Succeed())
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			source := &countingSource{}
+			_, err := aura.NewFileTokenSource(path, token(source)).Token(ctx)
+			Expect(err).To(MatchError(context.DeadlineExceeded))
+			Expect(source.calls).To(Equal(0))
+		})
+	})
 	Describe("Retrying requests", func() {
 		It("should not happen by default", func() {
 			responseMap[GET_INSTANCE] = mockError(500)
@@ -247,6 +667,15 @@ var _ = Describe("Aura", Ordered, func() {
 			Expect(err).NotTo(Succeed())
 			Expect(callCounter[GET_INSTANCE]).To(Equal(1))
 		})
+		It("should not happen when creating instances", func() {
+			client, err = aura.NewClient(context.Background(), "foo", "bar", "mox",
+				aura.WithRetries(2),
+				aura.WithEndpoint(server.URL))
+			responseMap[CREATE_INSTANCE] = mockError(http.StatusServiceUnavailable)
+			_, err := client.CreateInstance("foo", "gcp", "8GB", "5", "europe-west1", "enterprise-db")
+			Expect(err).To(MatchError(aura.ErrServer))
+			Expect(callCounter[CREATE_INSTANCE]).To(Equal(1))
+		})
 	})
 	Describe("Creating an instance", func() {
 		It("should create a POST request to the Aura API", func() {
@@ -280,6 +709,127 @@ var _ = Describe("Aura", Ordered, func() {
 			Expect(actual.Data.Name).To(Equal("foo"))
 		})
 	})
+	Describe("Listing instances", func() {
+		var tenant string
+		BeforeEach(func() {
+			tenant = ""
+			responseMap[LIST_INSTANCES] = func(w http.ResponseWriter, r *http.Request) error {
+				tenant = r.URL.Query().Get("tenantId")
+				m := map[string]any{
+					"data": []any{
+						map[string]any{
+							"id":             "abc123",
+							"name":           "Production",
+							"tenant_id":      "YOUR_TENANT_ID",
+							"cloud_provider": "gcp",
+						},
+						map[string]any{
+							"id":             "def456",
+							"name":           "Staging",
+							"tenant_id":      "YOUR_TENANT_ID",
+							"cloud_provider": "aws",
+						},
+					},
+				}
+				b, err := json.Marshal(m)
+				if err != nil {
+					panic(err)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("X-Request-Id", responseId)
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write(b)
+				return nil
+			}
+		})
+		It("should return all instances when no tenant is given", func() {
+			actual, err := client.ListInstances("")
+			Expect(err).To(Succeed())
+			Expect(tenant).To(BeEmpty())
+			Expect(actual.Data).To(HaveLen(2))
+			Expect(actual.Data[0].ID).To(Equal("abc123"))
+			Expect(actual.Data[1].CloudProvider).To(Equal("aws"))
+		})
+		It("should filter on the given tenant", func() {
+			_, err := client.ListInstances("YOUR_TENANT_ID")
+			Expect(err).To(Succeed())
+			Expect(tenant).To(Equal("YOUR_TENANT_ID"))
+		})
+		It("should fail on error response codes", func() {
+			responseMap[LIST_INSTANCES] = mockError(http.StatusForbidden)
+			_, err := client.ListInstances("")
+			Expect(err).NotTo(Succeed())
+			Expect(err.Error()).To(ContainSubstring(responseId))
+		})
+	})
+	Describe("Creating an instance from a request", func() {
+		var body map[string]any
+		BeforeEach(func() {
+			body = nil
+			create := mockJSON(http.StatusAccepted, map[string]any{
+				"data": map[string]any{"id": "db1d1234", "name": "foo"},
+			})
+			responseMap[CREATE_INSTANCE] = func(w http.ResponseWriter, r *http.Request) error {
+				err := json.NewDecoder(r.Body).Decode(&body)
+				if err != nil {
+					return err
+				}
+				return create(w, r)
+			}
+		})
+		It("should send the required fields and the tenant of the client", func() {
+			actual, err := client.CreateInstanceFromRequest(aura.CreateInstanceRequest{
+				Name:          "foo",
+				CloudProvider: aura.CloudProviderGCP,
+				Region:        "europe-west1",
+				InstanceType:  aura.InstanceTypeEnterpriseDB,
+				Memory:        "8GB",
+				Version:       "5",
+			})
+			Expect(err).To(Succeed())
+			Expect(actual.Data.ID).To(Equal("db1d1234"))
+			Expect(body).To(Equal(map[string]any{
+				"name":           "foo",
+				"tenant_id":      "mox",
+				"cloud_provider": "gcp",
+				"region":         "europe-west1",
+				"type":           "enterprise-db",
+				"memory":         "8GB",
+				"version":        "5",
+			}))
+		})
+		It("should send the optional fields when set", func() {
+			_, err := client.CreateInstanceFromRequest(aura.CreateInstanceRequest{
+				Name:                 "foo",
+				TenantID:             "other-tenant",
+				CloudProvider:        aura.CloudProviderAWS,
+				Region:               "us-east-1",
+				InstanceType:         aura.InstanceTypeBusinessCritical,
+				Memory:               "8GB",
+				Version:              "5",
+				Storage:              "32GB",
+				CustomerManagedKeyID: "key-1",
+				GraphAnalyticsPlugin: true,
+				VectorOptimized:      true,
+				SourceInstanceID:     "abc123",
+			})
+			Expect(err).To(Succeed())
+			Expect(body).To(HaveKeyWithValue("tenant_id", "other-tenant"))
+			Expect(body).To(HaveKeyWithValue("storage", "32GB"))
+			Expect(body).To(HaveKeyWithValue("customer_managed_key_id", "key-1"))
+			Expect(body).To(HaveKeyWithValue("graph_analytics_plugin", true))
+			Expect(body).To(HaveKeyWithValue("vector_optimized", true))
+			Expect(body).To(HaveKeyWithValue("source_instance_id", "abc123"))
+			Expect(body).NotTo(HaveKey("source_snapshot_id"))
+		})
+		It("should be used by CreateInstance", func() {
+			_, err := client.CreateInstance("foo", "gcp", "2GB", "5", "europe-west1", "enterprise-db")
+			Expect(err).To(Succeed())
+			Expect(body).To(HaveLen(7))
+			Expect(body).To(HaveKeyWithValue("memory", "2GB"))
+			Expect(body).To(HaveKeyWithValue("region", "europe-west1"))
+		})
+	})
 	Describe("Getting an instance", func() {
 		It("should return the instance info when succesful", func() {
 			mockGet("abc123")
@@ -358,4 +908,1328 @@ var _ = Describe("Aura", Ordered, func() {
 			Expect(err).To(Succeed())
 		})
 	})
+	Describe("Resuming an instance", func() {
+		It("should create a POST request to the right URL", func() {
+			f := func(w http.ResponseWriter, r *http.Request) error {
+				m := map[string]any{
+					"data": map[string]any{
+						"id":             "abc123",
+						"name":           "Production",
+						"status":         "resuming",
+						"connection_url": "YOUR_CONNECTION_URL",
+						"tenant_id":      "YOUR_TENANT_ID",
+						"cloud_provider": "gcp",
+						"memory":         "8GB",
+						"region":         "europe-west1",
+						"type":           "enterprise-db",
+					},
+				}
+				b, err := json.Marshal(m)
+				if err != nil {
+					panic(err)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("X-Request-Id", responseId)
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write(b)
+				return nil
+			}
+			responseMap[RESUME_INSTANCE] = f
+			err := client.ResumeInstance("abc123")
+			Expect(err).To(Succeed())
+			Expect(callCounter[RESUME_INSTANCE]).To(Equal(1))
+		})
+		It("should treat an already running instance as success", func() {
+			responseMap[RESUME_INSTANCE] = mockError(http.StatusBadRequest)
+			mockGet("abc123")
+			err := client.ResumeInstance("abc123")
+			Expect(err).To(Succeed())
+			Expect(callCounter[GET_INSTANCE]).To(Equal(1))
+		})
+		It("should fail when the instance could not be resumed", func() {
+			responseMap[RESUME_INSTANCE] = mockError(http.StatusBadRequest)
+			responseMap[GET_INSTANCE] = mockError(http.StatusNotFound)
+			err := client.ResumeInstance("abc123")
+			Expect(err).NotTo(Succeed())
+			Expect(err.Error()).To(ContainSubstring(responseId))
+		})
+		It("should fail on other response codes", func() {
+			responseMap[RESUME_INSTANCE] = mockError(http.StatusInternalServerError)
+			err := client.ResumeInstance("abc123")
+			Expect(err).NotTo(Succeed())
+			Expect(callCounter[GET_INSTANCE]).To(Equal(0))
+		})
+	})
+	Describe("Updating an instance", func() {
+		var body map[string]any
+		BeforeEach(func() {
+			body = nil
+			responseMap[UPDATE_INSTANCE] = func(w http.ResponseWriter, r *http.Request) error {
+				err := json.NewDecoder(r.Body).Decode(&body)
+				if err != nil {
+					return err
+				}
+				m := map[string]any{
+					"data": map[string]any{
+						"id":             "abc123",
+						"name":           "Production",
+						"status":         "updating",
+						"connection_url": "YOUR_CONNECTION_URL",
+						"tenant_id":      "YOUR_TENANT_ID",
+						"cloud_provider": "gcp",
+						"memory":         "8GB",
+						"region":         "europe-west1",
+						"type":           "enterprise-db",
+					},
+				}
+				b, err := json.Marshal(m)
+				if err != nil {
+					panic(err)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("X-Request-Id", responseId)
+				w.WriteHeader(http.StatusAccepted)
+				_, _ = w.Write(b)
+				return nil
+			}
+		})
+		It("should only send the fields that were set", func() {
+			actual, err := client.UpdateInstance("abc123", aura.UpdateRequest{Memory: "16GB"})
+			Expect(err).To(Succeed())
+			Expect(body).To(Equal(map[string]any{"memory": "16GB"}))
+			Expect(actual.Data.Status).To(Equal(aura.StatusUpdating))
+		})
+		It("should send both name and memory when set", func() {
+			_, err := client.UpdateInstance("abc123", aura.UpdateRequest{Name: "Staging", Memory: "16GB"})
+			Expect(err).To(Succeed())
+			Expect(body).To(Equal(map[string]any{"name": "Staging", "memory": "16GB"}))
+		})
+		It("should fail without calling the API when nothing is set", func() {
+			_, err := client.UpdateInstance("abc123", aura.UpdateRequest{})
+			Expect(err).NotTo(Succeed())
+			Expect(callCounter[UPDATE_INSTANCE]).To(Equal(0))
+		})
+		It("should fail on error response codes", func() {
+			responseMap[UPDATE_INSTANCE] = mockError(http.StatusBadRequest)
+			_, err := client.UpdateInstance("abc123", aura.UpdateRequest{Name: "Staging"})
+			Expect(err).NotTo(Succeed())
+			Expect(err.Error()).To(ContainSubstring(responseId))
+		})
+	})
+	Describe("Snapshots", func() {
+		It("should be listed for an instance", func() {
+			var date string
+			list := mockJSON(http.StatusOK, map[string]any{
+				"data": []any{
+					mockedSnapshot("abc123", "snap-1"),
+					mockedSnapshot("abc123", "snap-2"),
+				},
+			})
+			responseMap[LIST_SNAPSHOTS] = func(w http.ResponseWriter, r *http.Request) error {
+				date = r.URL.Query().Get("date")
+				return list(w, r)
+			}
+			actual, err := client.ListSnapshots("abc123", "2024-01-31")
+			Expect(err).To(Succeed())
+			Expect(date).To(Equal("2024-01-31"))
+			Expect(actual.Data).To(HaveLen(2))
+			Expect(actual.Data[1].SnapshotID).To(Equal("snap-2"))
+			Expect(actual.Data[1].InstanceID).To(Equal("abc123"))
+		})
+		It("should be taken on demand", func() {
+			responseMap[CREATE_SNAPSHOT] = mockJSON(http.StatusAccepted, map[string]any{
+				"data": map[string]any{"snapshot_id": "snap-1"},
+			})
+			actual, err := client.CreateSnapshot("abc123")
+			Expect(err).To(Succeed())
+			Expect(actual.Data.SnapshotID).To(Equal("snap-1"))
+		})
+		It("should return a single snapshot", func() {
+			responseMap[GET_SNAPSHOT] = mockJSON(http.StatusOK, map[string]any{
+				"data": mockedSnapshot("abc123", "snap-1"),
+			})
+			actual, err := client.GetSnapshot("abc123", "snap-1")
+			Expect(err).To(Succeed())
+			Expect(actual.Data.Status).To(Equal(aura.SnapshotStatusCompleted))
+			Expect(actual.Data.Profile).To(Equal("AddHoc"))
+			Expect(actual.Data.Timestamp).To(Equal("2024-01-31T12:00:00Z"))
+		})
+		It("should restore an instance", func() {
+			responseMap[RESTORE_SNAPSHOT] = mockJSON(http.StatusAccepted, map[string]any{
+				"data": map[string]any{
+					"id":     "abc123",
+					"name":   "Production",
+					"status": "restoring",
+				},
+			})
+			actual, err := client.RestoreSnapshot("abc123", "snap-1")
+			Expect(err).To(Succeed())
+			Expect(actual.Data.Status).To(Equal(aura.StatusRestoring))
+			Expect(callCounter[RESTORE_SNAPSHOT]).To(Equal(1))
+		})
+		It("should wrap failures in an AuraError", func() {
+			responseMap[GET_SNAPSHOT] = mockError(http.StatusNotFound)
+			_, err := client.GetSnapshot("abc123", "snap-1")
+			Expect(err).NotTo(Succeed())
+			var auraErr *aura.AuraError
+			Expect(errors.As(err, &auraErr)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring(responseId))
+		})
+	})
+	Describe("Overwriting an instance", func() {
+		var body map[string]any
+		BeforeEach(func() {
+			body = nil
+			overwrite := mockJSON(http.StatusAccepted, map[string]any{
+				"data": map[string]any{
+					"id":     "abc123",
+					"name":   "Staging",
+					"status": "overwriting",
+				},
+			})
+			responseMap[OVERWRITE_INSTANCE] = func(w http.ResponseWriter, r *http.Request) error {
+				err := json.NewDecoder(r.Body).Decode(&body)
+				if err != nil {
+					return err
+				}
+				return overwrite(w, r)
+			}
+		})
+		It("should use a source instance", func() {
+			actual, err := client.OverwriteInstance("abc123", "def456", "")
+			Expect(err).To(Succeed())
+			Expect(body).To(Equal(map[string]any{"source_instance_id": "def456"}))
+			Expect(actual.Data.Status).To(Equal(aura.StatusOverwriting))
+		})
+		It("should use a source snapshot", func() {
+			_, err := client.OverwriteInstance("abc123", "", "snap-1")
+			Expect(err).To(Succeed())
+			Expect(body).To(Equal(map[string]any{"source_snapshot_id": "snap-1"}))
+		})
+		It("should require exactly one source", func() {
+			_, err := client.OverwriteInstance("abc123", "", "")
+			Expect(err).NotTo(Succeed())
+			_, err = client.OverwriteInstance("abc123", "def456", "snap-1")
+			Expect(err).NotTo(Succeed())
+			Expect(callCounter[OVERWRITE_INSTANCE]).To(Equal(0))
+		})
+		It("should fail on error response codes", func() {
+			responseMap[OVERWRITE_INSTANCE] = mockError(http.StatusConflict)
+			_, err := client.OverwriteInstance("abc123", "def456", "")
+			Expect(err).NotTo(Succeed())
+			Expect(err.Error()).To(ContainSubstring(responseId))
+		})
+	})
+	Describe("Tenants", func() {
+		It("should be listed", func() {
+			responseMap[LIST_TENANTS] = mockJSON(http.StatusOK, map[string]any{
+				"data": []any{
+					map[string]any{"id": "tenant-1", "name": "Production"},
+					map[string]any{"id": "tenant-2", "name": "Staging"},
+				},
+			})
+			actual, err := client.ListTenants()
+			Expect(err).To(Succeed())
+			Expect(actual.Data).To(HaveLen(2))
+			Expect(actual.Data[1].ID).To(Equal("tenant-2"))
+			Expect(actual.Data[1].Name).To(Equal("Staging"))
+		})
+		It("should return the allowed instance configurations", func() {
+			responseMap[GET_TENANT] = mockJSON(http.StatusOK, map[string]any{
+				"data": mockedTenant("tenant-1"),
+			})
+			actual, err := client.GetTenant("tenant-1")
+			Expect(err).To(Succeed())
+			Expect(actual.Data.ID).To(Equal("tenant-1"))
+			Expect(actual.Data.InstanceConfigurations).To(HaveLen(3))
+			Expect(actual.Data.InstanceConfigurations[2]).To(Equal(aura.InstanceConfiguration{
+				CloudProvider: "aws",
+				Region:        "us-east-1",
+				RegionName:    "US East, N. Virginia (us-east-1)",
+				Memory:        "8GB",
+				Storage:       "16GB",
+				InstanceType:  "enterprise-db",
+				Version:       "5",
+			}))
+		})
+		It("should fail on error response codes", func() {
+			responseMap[GET_TENANT] = mockError(http.StatusNotFound)
+			_, err := client.GetTenant("tenant-1")
+			Expect(err).NotTo(Succeed())
+			Expect(err.Error()).To(ContainSubstring(responseId))
+		})
+	})
+	Describe("Customer managed keys", func() {
+		key := func(status aura.CustomerManagedKeyStatus) map[string]any {
+			return map[string]any{
+				"id":             "key123",
+				"name":           "Production key",
+				"tenant_id":      "mox",
+				"status":         status,
+				"created":        "2024-01-31T12:00:00Z",
+				"cloud_provider": "aws",
+				"key_id":         "arn:aws:kms:us-east-1:123456789012:key/abc",
+				"region":         "us-east-1",
+				"type":           "enterprise-db",
+			}
+		}
+		// mockKeyStatuses makes the key report the given statuses in order,
+		// repeating the last one once they have all been reported.
+		mockKeyStatuses := func(statuses ...aura.CustomerManagedKeyStatus) {
+			i := 0
+			responseMap[GET_KEY] = func(w http.ResponseWriter, r *http.Request) error {
+				status := statuses[min(i, len(statuses)-1)]
+				i++
+				return mockJSON(http.StatusOK, map[string]any{"data": key(status)})(w, r)
+			}
+		}
+		fast := aura.WithPollInterval(time.Millisecond, time.Millisecond)
+		It("should be listed for a tenant", func() {
+			responseMap[LIST_KEYS] = func(w http.ResponseWriter, r *http.Request) error {
+				Expect(r.URL.Query().Get("tenantId")).To(Equal("mox"))
+				return mockJSON(http.StatusOK, map[string]any{
+					"data": []any{map[string]any{"id": "key123", "name": "Production key", "tenant_id": "mox"}},
+				})(w, r)
+			}
+			actual, err := client.ListCustomerManagedKeys("mox")
+			Expect(err).To(Succeed())
+			Expect(actual.Data).To(Equal([]aura.CustomerManagedKeySummary{
+				{ID: "key123", Name: "Production key", TenantID: "mox"},
+			}))
+		})
+		It("should be created in the tenant of the client", func() {
+			responseMap[CREATE_KEY] = func(w http.ResponseWriter, r *http.Request) error {
+				var body map[string]any
+				Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+				Expect(body).To(Equal(map[string]any{
+					"name":           "Production key",
+					"tenant_id":      "mox",
+					"key_id":         "arn:aws:kms:us-east-1:123456789012:key/abc",
+					"cloud_provider": "aws",
+					"region":         "us-east-1",
+					"type":           "enterprise-db",
+				}))
+				return mockJSON(http.StatusAccepted, map[string]any{
+					"data": key(aura.CustomerManagedKeyStatusPending),
+				})(w, r)
+			}
+			actual, err := client.CreateCustomerManagedKey(aura.CreateCustomerManagedKeyRequest{
+				Name:          "Production key",
+				KeyID:         "arn:aws:kms:us-east-1:123456789012:key/abc",
+				CloudProvider: aura.CloudProviderAWS,
+				Region:        "us-east-1",
+				InstanceType:  aura.InstanceTypeEnterpriseDB,
+			})
+			Expect(err).To(Succeed())
+			Expect(actual.Data.ID).To(Equal("key123"))
+			Expect(actual.Data.Status).To(Equal(aura.CustomerManagedKeyStatusPending))
+		})
+		It("should return a single key", func() {
+			mockKeyStatuses(aura.CustomerManagedKeyStatusReady)
+			actual, err := client.GetCustomerManagedKey("key123")
+			Expect(err).To(Succeed())
+			Expect(actual.Data.KeyID).To(Equal("arn:aws:kms:us-east-1:123456789012:key/abc"))
+			Expect(actual.Data.CloudProvider).To(Equal(aura.CloudProviderAWS))
+		})
+		It("should be deleted, treating 404 as success", func() {
+			responseMap[DELETE_KEY] = mockJSON(http.StatusNoContent, nil)
+			Expect(client.DeleteCustomerManagedKey("key123")).To(Succeed())
+			responseMap[DELETE_KEY] = mockError(http.StatusNotFound)
+			Expect(client.DeleteCustomerManagedKey("key123")).To(Succeed())
+			responseMap[DELETE_KEY] = mockError(http.StatusConflict)
+			Expect(client.DeleteCustomerManagedKey("key123")).To(MatchError(aura.ErrConflict))
+		})
+		It("should be waited for until ready", func() {
+			mockKeyStatuses(aura.CustomerManagedKeyStatusPending, aura.CustomerManagedKeyStatusPending,
+				aura.CustomerManagedKeyStatusReady)
+			actual, err := client.WaitForCustomerManagedKey(context.Background(), "key123", fast)
+			Expect(err).To(Succeed())
+			Expect(actual.Data.Status).To(Equal(aura.CustomerManagedKeyStatusReady))
+			Expect(callCounter[GET_KEY]).To(Equal(3))
+		})
+		It("should fail waiting when the key cannot be used", func() {
+			mockKeyStatuses(aura.CustomerManagedKeyStatusPending, aura.CustomerManagedKeyStatusError)
+			_, err := client.WaitForCustomerManagedKey(context.Background(), "key123", fast)
+			var failure *aura.FailureStatusError
+			Expect(errors.As(err, &failure)).To(BeTrue())
+			Expect(failure.Status).To(Equal("error"))
+		})
+		It("should be referenced when creating instances", func() {
+			responseMap[CREATE_INSTANCE] = func(w http.ResponseWriter, r *http.Request) error {
+				var body map[string]any
+				Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+				Expect(body).To(HaveKeyWithValue("customer_managed_key_id", "key123"))
+				return mockJSON(http.StatusAccepted, map[string]any{"data": map[string]any{"id": "abc123"}})(w, r)
+			}
+			_, err := client.CreateInstanceFromRequest(aura.CreateInstanceRequest{
+				Name:                 "Production",
+				CloudProvider:        aura.CloudProviderAWS,
+				Region:               "us-east-1",
+				InstanceType:         aura.InstanceTypeEnterpriseDB,
+				Memory:               "8GB",
+				Version:              "5",
+				CustomerManagedKeyID: "key123",
+			})
+			Expect(err).To(Succeed())
+		})
+	})
+	Describe("Graph Analytics sessions", func() {
+		session := func(status aura.SessionStatus) map[string]any {
+			return map[string]any{
+				"id":          "session123",
+				"name":        "PageRank",
+				"tenant_id":   "mox",
+				"instance_id": "abc123",
+				"status":      status,
+				"memory":      "16GB",
+				"host":        "session123.sessions.neo4j.io",
+				"ttl":         "30m",
+				"created_at":  "2024-01-31T12:00:00Z",
+				"expiry_date": "2024-01-31T12:30:00Z",
+			}
+		}
+		fast := aura.WithPollInterval(time.Millisecond, time.Millisecond)
+		It("should estimate the memory of a session", func() {
+			responseMap[SIZE_SESSION] = func(w http.ResponseWriter, r *http.Request) error {
+				var body map[string]any
+				Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+				Expect(body).To(Equal(map[string]any{
+					"node_count":           float64(1000000),
+					"relationship_count":   float64(5000000),
+					"algorithm_categories": []any{"centrality", "path-finding"},
+				}))
+				return mockJSON(http.StatusOK, map[string]any{"data": map[string]any{
+					"estimated_memory":   "3GB",
+					"recommended_size":   "4GB",
+					"did_exceed_maximum": false,
+				}})(w, r)
+			}
+			actual, err := client.EstimateSessionSize(aura.SessionSizeRequest{
+				NodeCount:         1_000_000,
+				RelationshipCount: 5_000_000,
+				AlgorithmCategories: []aura.AlgorithmCategory{
+					aura.AlgorithmCategoryCentrality, aura.AlgorithmCategoryPathFinding,
+				},
+			})
+			Expect(err).To(Succeed())
+			Expect(actual.Data).To(Equal(aura.SessionSize{EstimatedMemory: "3GB", RecommendedSize: "4GB"}))
+		})
+		It("should be listed for a tenant and instance", func() {
+			responseMap[LIST_SESSIONS] = func(w http.ResponseWriter, r *http.Request) error {
+				Expect(r.URL.Query().Get("tenantId")).To(Equal("mox"))
+				Expect(r.URL.Query().Get("instanceId")).To(Equal("abc123"))
+				return mockJSON(http.StatusOK, map[string]any{"data": []any{session(aura.SessionStatusReady)}})(w, r)
+			}
+			actual, err := client.ListSessions("mox", "abc123")
+			Expect(err).To(Succeed())
+			Expect(actual.Data).To(Equal([]aura.SessionSummary{
+				{ID: "session123", Name: "PageRank", TenantID: "mox", InstanceID: "abc123"},
+			}))
+		})
+		It("should be created in the tenant of the client", func() {
+			responseMap[CREATE_SESSION] = func(w http.ResponseWriter, r *http.Request) error {
+				var body map[string]any
+				Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+				Expect(body).To(Equal(map[string]any{
+					"name":        "PageRank",
+					"tenant_id":   "mox",
+					"instance_id": "abc123",
+					"memory":      "16GB",
+					"ttl":         "30m",
+				}))
+				return mockJSON(http.StatusAccepted, map[string]any{"data": session(aura.SessionStatusCreating)})(w, r)
+			}
+			actual, err := client.CreateSession(aura.CreateSessionRequest{
+				Name:       "PageRank",
+				InstanceID: "abc123",
+				Memory:     "16GB",
+				TTL:        "30m",
+			})
+			Expect(err).To(Succeed())
+			Expect(actual.Data.ID).To(Equal("session123"))
+			Expect(actual.Data.Status).To(Equal(aura.SessionStatusCreating))
+		})
+		It("should be deleted, treating 404 as success", func() {
+			responseMap[DELETE_SESSION] = mockJSON(http.StatusNoContent, nil)
+			Expect(client.DeleteSession("session123")).To(Succeed())
+			responseMap[DELETE_SESSION] = mockError(http.StatusNotFound)
+			Expect(client.DeleteSession("session123")).To(Succeed())
+		})
+		It("should be waited for until ready", func() {
+			statuses := []aura.SessionStatus{aura.SessionStatusCreating, aura.SessionStatusReady}
+			responseMap[GET_SESSION] = func(w http.ResponseWriter, r *http.Request) error {
+				status := statuses[min(callCounter[GET_SESSION], len(statuses)-1)]
+				return mockJSON(http.StatusOK, map[string]any{"data": session(status)})(w, r)
+			}
+			actual, err := client.WaitForSession(context.Background(), "session123", fast)
+			Expect(err).To(Succeed())
+			Expect(actual.Data.Host).To(Equal("session123.sessions.neo4j.io"))
+			Expect(callCounter[GET_SESSION]).To(Equal(2))
+		})
+		It("should fail waiting when the session expired", func() {
+			responseMap[GET_SESSION] = mockJSON(http.StatusOK, map[string]any{"data": session(aura.SessionStatusExpired)})
+			_, err := client.WaitForSession(context.Background(), "session123", fast)
+			var failure *aura.FailureStatusError
+			Expect(errors.As(err, &failure)).To(BeTrue())
+			Expect(failure.Status).To(Equal("Expired"))
+		})
+	})
+	Describe("Metrics integration", func() {
+		exposition := `# HELP neo4j_aura_cpu_usage CPU usage (cores)
+# TYPE neo4j_aura_cpu_usage gauge
+neo4j_aura_cpu_usage{aggregation="MIN",instance_id="abc123"} 0.25 1706702400000
+neo4j_aura_cpu_usage{aggregation="MAX",instance_id="abc123"} 1.5 1706702400000
+neo4j_aura_cpu_usage{aggregation="MAX",instance_id="def456"} 0.5 1706702400000
+neo4j_aura_cpu_limit{aggregation="MAX",instance_id="abc123"} 2 1706702400000
+neo4j_dbms_vm_heap_used_ratio{aggregation="MAX",instance_id="abc123"} 0.75 1706702400000
+neo4j_database_store_size_database{aggregation="MAX",database="neo4j",instance_id="abc123"} 1.5e+09
+neo4j_database_store_size_database{aggregation="MAX",database="system",instance_id="abc123"} 5e+08
+neo4j_db_query_execution_success_total{aggregation="MAX",database="neo4j",instance_id="abc123"} 600
+neo4j_db_query_execution_failure_total{aggregation="MAX",database="neo4j",instance_id="abc123"} 3
+`
+		It("should return the endpoint of a tenant", func() {
+			responseMap[METRICS_INTEGRATION] = mockJSON(http.StatusOK, map[string]any{
+				"data": map[string]any{"endpoint": "https://customer-metrics-api.neo4j.io/api/v1/mox/metrics"},
+			})
+			actual, err := client.GetMetricsIntegration("mox")
+			Expect(err).To(Succeed())
+			Expect(actual.Data.Endpoint).To(Equal("https://customer-metrics-api.neo4j.io/api/v1/mox/metrics"))
+		})
+		It("should scrape the endpoint using the token of the client", func() {
+			responseMap[SCRAPE_METRICS] = func(w http.ResponseWriter, r *http.Request) error {
+				Expect(r.Header.Get("Authorization")).To(HavePrefix("Bearer "))
+				w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+				_, err := io.WriteString(w, exposition)
+				return err
+			}
+			actual, err := client.ScrapeMetrics(server.URL + "/api/v1/mox/metrics")
+			Expect(err).To(Succeed())
+			Expect(actual.Samples).To(HaveLen(9))
+			Expect(actual.Samples[0]).To(Equal(aura.MetricSample{
+				Name:      aura.AuraMetricCPUUsage,
+				Labels:    map[string]string{"aggregation": "MIN", "instance_id": "abc123"},
+				Value:     0.25,
+				Timestamp: time.UnixMilli(1706702400000),
+			}))
+			Expect(actual.Instances).To(HaveLen(2))
+			Expect(*actual.Instances["abc123"]).To(Equal(aura.InstanceMetrics{
+				InstanceID:       "abc123",
+				CPUUsage:         1.5,
+				CPULimit:         2,
+				HeapUsedRatio:    0.75,
+				StoreSize:        2e9,
+				QueryRate:        600,
+				FailedQueryRate:  3,
+				LatestSampleTime: time.UnixMilli(1706702400000),
+			}))
+		})
+		It("should fail scraping when the endpoint fails", func() {
+			responseMap[SCRAPE_METRICS] = mockError(http.StatusForbidden)
+			_, err := client.ScrapeMetrics(server.URL + "/api/v1/mox/metrics")
+			Expect(err).To(MatchError(aura.ErrForbidden))
+		})
+		It("should refuse to scrape endpoints not served by Aura", func() {
+			other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+				Fail("Unexpected request with Authorization " + r.Header.Get("Authorization"))
+			}))
+			defer other.Close()
+			for _, endpoint := range []string{
+				other.URL + "/api/v1/mox/metrics",
+				"http://customer-metrics-api.neo4j.io/api/v1/mox/metrics",
+				"https://customer-metrics-api.neo4j.io.example.com/api/v1/mox/metrics",
+			} {
+				_, err := client.ScrapeMetrics(endpoint)
+				Expect(err).To(MatchError(ContainSubstring("refusing to send the token")), endpoint)
+			}
+			Expect(callCounter[AUTHENTICATE]).To(Equal(0))
+		})
+		It("should parse escaped labels and special values", func() {
+			samples, err := aura.ParseMetrics(strings.NewReader(
+				"up 1\n" +
+					`info{path="C:\\data",quote="say \"hi\"",lines="a\nb"} NaN` + "\n" +
+					`limit{le="+Inf",} +Inf` + "\n"))
+			Expect(err).To(Succeed())
+			Expect(samples).To(HaveLen(3))
+			Expect(samples[0]).To(Equal(aura.MetricSample{Name: "up", Labels: map[string]string{}, Value: 1}))
+			Expect(samples[1].Labels).To(Equal(map[string]string{
+				"path": `C:\data`, "quote": `say "hi"`, "lines": "a\nb",
+			}))
+			Expect(math.IsNaN(samples[1].Value)).To(BeTrue())
+			Expect(samples[2].Labels).To(Equal(map[string]string{"le": "+Inf"}))
+			Expect(math.IsInf(samples[2].Value, 1)).To(BeTrue())
+		})
+		It("should reject malformed samples", func() {
+			for _, line := range []string{"up", `up{job="a" 1`, "up one", "up 1 2 3", `up{job} 1`} {
+				_, err := aura.ParseMetrics(strings.NewReader("# TYPE up gauge\n" + line + "\n"))
+				Expect(err).To(MatchError(ContainSubstring("line 2")), line)
+			}
+		})
+	})
+	Describe("Validating instance configurations", func() {
+		BeforeEach(func() {
+			client, err = aura.NewClient(context.Background(), "foo", "bar", "tenant-1",
+				aura.WithEndpoint(server.URL),
+				aura.WithConfigValidation())
+			if err != nil {
+				panic(err)
+			}
+			responseMap[GET_TENANT] = mockJSON(http.StatusOK, map[string]any{
+				"data": mockedTenant("tenant-1"),
+			})
+			responseMap[CREATE_INSTANCE] = mockJSON(http.StatusAccepted, map[string]any{
+				"data": map[string]any{"id": "db1d1234", "name": "foo"},
+			})
+		})
+		It("should create instances with an allowed configuration", func() {
+			_, err := client.CreateInstance("foo", "gcp", "8GB", "5", "europe-west1", "enterprise-db")
+			Expect(err).To(Succeed())
+			_, err = client.CreateInstance("bar", "aws", "8GB", "5", "us-east-1", "enterprise-db")
+			Expect(err).To(Succeed())
+			Expect(callCounter[CREATE_INSTANCE]).To(Equal(2))
+			// The configurations are cached
+			Expect(callCounter[GET_TENANT]).To(Equal(1))
+		})
+		It("should reject unsupported configurations before creating", func() {
+			// Region and memory swapped
+			_, err := client.CreateInstance("foo", "gcp", "europe-west1", "5", "8GB", "enterprise-db")
+			Expect(err).NotTo(Succeed())
+			Expect(callCounter[CREATE_INSTANCE]).To(Equal(0))
+			var confErr *aura.ConfigurationError
+			Expect(errors.As(err, &confErr)).To(BeTrue())
+			Expect(confErr.TenantID).To(Equal("tenant-1"))
+			Expect(confErr.Requested.Region).To(Equal("8GB"))
+			Expect(confErr.Alternatives).To(HaveLen(2))
+			Expect(confErr.Alternatives[0].CloudProvider).To(Equal("gcp"))
+			Expect(err.Error()).To(ContainSubstring("region=europe-west1"))
+		})
+		It("should list the nearest alternatives", func() {
+			_, err := client.CreateInstance("foo", "gcp", "32GB", "5", "europe-west1", "enterprise-db")
+			var confErr *aura.ConfigurationError
+			Expect(errors.As(err, &confErr)).To(BeTrue())
+			Expect(confErr.Alternatives).To(HaveLen(2))
+			Expect(confErr.Alternatives[0].Memory).To(Equal("8GB"))
+			Expect(confErr.Alternatives[1].Memory).To(Equal("16GB"))
+		})
+		It("should use the configurations of the tenant of the instance", func() {
+			responseMap[GET_TENANT] = func(w http.ResponseWriter, r *http.Request) error {
+				tenant := mockedTenant(path.Base(r.URL.Path))
+				if tenant["id"] == "tenant-2" {
+					// Only the AWS configuration is allowed
+					tenant["instance_configurations"] = tenant["instance_configurations"].([]any)[2:]
+				}
+				return mockJSON(http.StatusOK, map[string]any{"data": tenant})(w, r)
+			}
+			request := aura.CreateInstanceRequest{
+				Name:          "foo",
+				TenantID:      "tenant-2",
+				CloudProvider: aura.CloudProviderGCP,
+				Region:        "europe-west1",
+				InstanceType:  aura.InstanceTypeEnterpriseDB,
+				Memory:        "8GB",
+				Version:       "5",
+			}
+			_, err := client.CreateInstanceFromRequest(request)
+			var confErr *aura.ConfigurationError
+			Expect(errors.As(err, &confErr)).To(BeTrue())
+			Expect(confErr.TenantID).To(Equal("tenant-2"))
+			Expect(confErr.Alternatives[0].CloudProvider).To(Equal("aws"))
+			Expect(callCounter[CREATE_INSTANCE]).To(Equal(0))
+
+			request.TenantID = ""
+			_, err = client.CreateInstanceFromRequest(request)
+			Expect(err).To(Succeed())
+			request.TenantID, request.CloudProvider, request.Region = "tenant-2", aura.CloudProviderAWS, "us-east-1"
+			_, err = client.CreateInstanceFromRequest(request)
+			Expect(err).To(Succeed())
+			Expect(callCounter[CREATE_INSTANCE]).To(Equal(2))
+			// The configurations are cached for each tenant
+			Expect(callCounter[GET_TENANT]).To(Equal(2))
+		})
+		It("should not hold up other tenants while fetching configurations", func() {
+			release := make(chan struct{})
+			slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.URL.Path == "/oauth/token":
+					_ = authSuccess(w, r)
+				case r.URL.Path == "/v1/tenants/tenant-2":
+					select {
+					case <-release:
+					case <-r.Context().Done():
+						return
+					}
+					_ = mockJSON(http.StatusOK, map[string]any{"data": mockedTenant("tenant-2")})(w, r)
+				case strings.HasPrefix(r.URL.Path, "/v1/tenants/"):
+					_ = mockJSON(http.StatusOK, map[string]any{"data": mockedTenant(path.Base(r.URL.Path))})(w, r)
+				default:
+					_ = mockJSON(http.StatusAccepted, map[string]any{
+						"data": map[string]any{"id": "db1d1234", "name": "foo"},
+					})(w, r)
+				}
+			}))
+			defer slow.Close()
+			defer close(release)
+			c, err := aura.NewClient(context.Background(), "foo", "bar", "tenant-1",
+				aura.WithEndpoint(slow.URL),
+				aura.WithConfigValidation())
+			Expect(err).To(Succeed())
+			request := aura.CreateInstanceRequest{
+				Name:          "foo",
+				CloudProvider: aura.CloudProviderGCP,
+				Region:        "europe-west1",
+				InstanceType:  aura.InstanceTypeEnterpriseDB,
+				Memory:        "8GB",
+				Version:       "5",
+			}
+			_, err = c.CreateInstanceFromRequest(request)
+			Expect(err).To(Succeed())
+
+			done := make(chan error, 1)
+			ctx, cancel := context.WithCancel(context.Background())
+			go func() {
+				r := request
+				r.TenantID = "tenant-2"
+				_, err := c.CreateInstanceFromRequestWithContext(ctx, r)
+				done <- err
+			}()
+			Consistently(done, 50*time.Millisecond).ShouldNot(Receive())
+			// Tenant 1 is not held up while the configurations of tenant 2 are fetched
+			created := make(chan error, 1)
+			go func() {
+				_, err := c.CreateInstanceFromRequest(request)
+				created <- err
+			}()
+			Eventually(created).Should(Receive(Succeed()))
+			cancel()
+			Eventually(done).Should(Receive(MatchError(context.Canceled)))
+		})
+		It("should not create when the configurations cannot be fetched", func() {
+			responseMap[GET_TENANT] = mockError(http.StatusForbidden)
+			_, err := client.CreateInstance("foo", "gcp", "8GB", "5", "europe-west1", "enterprise-db")
+			Expect(err).NotTo(Succeed())
+			Expect(callCounter[CREATE_INSTANCE]).To(Equal(0))
+		})
+		It("should not happen by default", func() {
+			client, err = aura.NewClient(context.Background(), "foo", "bar", "tenant-1",
+				aura.WithEndpoint(server.URL))
+			Expect(err).To(Succeed())
+			_, err := client.CreateInstance("foo", "gcp", "32GB", "5", "europe-west1", "enterprise-db")
+			Expect(err).To(Succeed())
+			Expect(callCounter[GET_TENANT]).To(Equal(0))
+		})
+	})
+	Describe("Contexts", func() {
+		It("should be used for the request", func() {
+			mockGet("abc123")
+			actual, err := client.GetInstanceWithContext(context.Background(), "abc123")
+			Expect(err).To(Succeed())
+			Expect(actual.Data.ID).To(Equal("abc123"))
+		})
+		It("should stop requests when cancelled", func() {
+			mockGet("abc123")
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, err := client.GetInstanceWithContext(ctx, "abc123")
+			Expect(err).To(MatchError(context.Canceled))
+			// Neither the token nor the instance was requested
+			Expect(callCounter[AUTHENTICATE]).To(Equal(0))
+			Expect(callCounter[GET_INSTANCE]).To(Equal(0))
+		})
+		It("should stop retrying when the deadline is exceeded", func() {
+			client, err = aura.NewClient(context.Background(), "foo", "bar", "mox",
+				aura.WithRetries(3),
+				aura.WithEndpoint(server.URL))
+			Expect(err).To(Succeed())
+			responseMap[GET_INSTANCE] = mockError(500)
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			start := time.Now()
+			_, err := client.GetInstanceWithContext(ctx, "abc123")
+			Expect(err).To(MatchError(context.DeadlineExceeded))
+			// The first backoff of the retries is a second
+			Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+			Expect(callCounter[GET_INSTANCE]).To(Equal(1))
+		})
+		It("should be passed on when validating configurations", func() {
+			client, err = aura.NewClient(context.Background(), "foo", "bar", "tenant-1",
+				aura.WithEndpoint(server.URL),
+				aura.WithConfigValidation())
+			Expect(err).To(Succeed())
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, err := client.CreateInstanceFromRequestWithContext(ctx, aura.CreateInstanceRequest{Name: "foo"})
+			Expect(err).To(MatchError(context.Canceled))
+			Expect(callCounter[GET_TENANT]).To(Equal(0))
+			Expect(callCounter[CREATE_INSTANCE]).To(Equal(0))
+		})
+	})
+	Describe("Waiting for a status", func() {
+		fast := aura.WithPollInterval(time.Millisecond, 5*time.Millisecond)
+		It("should poll until the target status is reached", func() {
+			mockStatuses("abc123", "creating", "creating", "running")
+			var seen []string
+			actual, err := client.WaitForStatus(context.Background(), "abc123", []string{aura.StatusRunning},
+				fast,
+				aura.WithProgress(func(status string) { seen = append(seen, status) }))
+			Expect(err).To(Succeed())
+			Expect(actual.Data.Status).To(Equal(aura.StatusRunning))
+			Expect(seen).To(Equal([]string{"creating", "creating", "running"}))
+			Expect(callCounter[GET_INSTANCE]).To(Equal(3))
+		})
+		It("should accept any of the target statuses", func() {
+			mockStatuses("abc123", "pausing", "paused")
+			actual, err := client.WaitForStatus(context.Background(), "abc123",
+				[]string{aura.StatusPaused, aura.StatusRunning}, fast)
+			Expect(err).To(Succeed())
+			Expect(actual.Data.Status).To(Equal(aura.StatusPaused))
+		})
+		It("should time out with the last observed status", func() {
+			mockStatuses("abc123", "creating")
+			_, err := client.WaitForStatus(context.Background(), "abc123", []string{aura.StatusRunning},
+				fast, aura.WithWaitTimeout(50*time.Millisecond))
+			var timeoutErr *aura.WaitTimeoutError
+			Expect(errors.As(err, &timeoutErr)).To(BeTrue())
+			Expect(timeoutErr.ID).To(Equal("abc123"))
+			Expect(timeoutErr.LastStatus).To(Equal(aura.StatusCreating))
+			Expect(err).To(MatchError(context.DeadlineExceeded))
+		})
+		It("should stop when the context is cancelled", func() {
+			mockStatuses("abc123", "creating")
+			ctx, cancel := context.WithCancel(context.Background())
+			_, err := client.WaitForStatus(ctx, "abc123", []string{aura.StatusRunning},
+				fast, aura.WithProgress(func(string) { cancel() }))
+			Expect(err).To(MatchError(context.Canceled))
+			Expect(callCounter[GET_INSTANCE]).To(Equal(1))
+		})
+		It("should fail on a failure status", func() {
+			mockStatuses("abc123", "creating", "destroying")
+			_, err := client.WaitForStatus(context.Background(), "abc123", []string{aura.StatusRunning},
+				fast, aura.WithFailureStatuses(aura.StatusDestroying))
+			var failureErr *aura.FailureStatusError
+			Expect(errors.As(err, &failureErr)).To(BeTrue())
+			Expect(failureErr.Status).To(Equal(aura.StatusDestroying))
+		})
+		It("should not poll continuously with invalid intervals", func() {
+			mockStatuses("abc123", "creating")
+			polls := 0
+			count := aura.WithProgress(func(string) { polls++ })
+			_, err := client.WaitForStatus(context.Background(), "abc123", []string{aura.StatusRunning},
+				aura.WithPollInterval(0, -time.Second), aura.WithWaitTimeout(50*time.Millisecond), count)
+			Expect(err).To(MatchError(context.DeadlineExceeded))
+			// The default interval of 5 seconds is used
+			Expect(polls).To(Equal(1))
+
+			polls = 0
+			_, err = client.WaitForStatus(context.Background(), "abc123", []string{aura.StatusRunning},
+				aura.WithPollInterval(20*time.Millisecond, time.Nanosecond), aura.WithWaitTimeout(100*time.Millisecond), count)
+			Expect(err).To(MatchError(context.DeadlineExceeded))
+			// Polling does not speed up to the maximum interval
+			Expect(polls).To(BeNumerically("<=", 7))
+		})
+		It("should fail when the instance cannot be fetched", func() {
+			responseMap[GET_INSTANCE] = mockError(http.StatusNotFound)
+			_, err := client.WaitForStatus(context.Background(), "abc123", []string{aura.StatusRunning}, fast)
+			var auraErr *aura.AuraError
+			Expect(errors.As(err, &auraErr)).To(BeTrue())
+		})
+	})
+	Describe("Errors", func() {
+		It("should expose the parsed response", func() {
+			responseMap[GET_INSTANCE] = mockError(http.StatusNotFound)
+			_, err := client.GetInstance("abc123")
+			var auraErr *aura.AuraError
+			Expect(errors.As(err, &auraErr)).To(BeTrue())
+			Expect(auraErr.StatusCode).To(Equal(http.StatusNotFound))
+			Expect(auraErr.RequestID).To(Equal(responseId))
+			Expect(auraErr.Errors).To(Equal([]aura.ErrorDetail{{
+				Message: "Server not responding.",
+				Reason:  "It is on fire",
+				Field:   "Ornithology",
+			}}))
+			// The body is captured before the response is closed
+			Expect(err.Error()).To(ContainSubstring("It is on fire"))
+		})
+		It("should keep bodies which are not JSON", func() {
+			responseMap[DESTROY_INSTANCE] = func(w http.ResponseWriter, r *http.Request) error {
+				w.Header().Set("X-Request-Id", responseId)
+				w.WriteHeader(http.StatusGone)
+				_, _ = w.Write([]byte(`410 Gone`))
+				return nil
+			}
+			err := client.DestroyInstance("abc123")
+			var auraErr *aura.AuraError
+			Expect(errors.As(err, &auraErr)).To(BeTrue())
+			Expect(auraErr.Body).To(Equal("410 Gone"))
+			Expect(auraErr.Errors).To(BeEmpty())
+		})
+		It("should be parsed after giving up retrying", func() {
+			responseMap[GET_INSTANCE] = mockError(http.StatusServiceUnavailable)
+			_, err := client.GetInstance("abc123")
+			Expect(err).To(MatchError(aura.ErrServer))
+			var auraErr *aura.AuraError
+			Expect(errors.As(err, &auraErr)).To(BeTrue())
+			Expect(auraErr.StatusCode).To(Equal(http.StatusServiceUnavailable))
+			Expect(auraErr.Errors).To(HaveLen(1))
+		})
+		DescribeTable("should match the sentinel of the status code",
+			func(code int, sentinel error) {
+				client, err = aura.NewClient(context.Background(), "foo", "bar", "mox",
+					aura.WithEndpoint(server.URL),
+					aura.WithRateLimitRetries(0))
+				Expect(err).To(Succeed())
+				responseMap[GET_INSTANCE] = mockError(code)
+				_, err := client.GetInstance("abc123")
+				Expect(errors.Is(err, sentinel)).To(BeTrue())
+				for _, other := range []error{
+					aura.ErrValidation, aura.ErrUnauthorized, aura.ErrForbidden, aura.ErrNotFound,
+					aura.ErrConflict, aura.ErrRateLimited, aura.ErrServer,
+				} {
+					if other != sentinel {
+						Expect(errors.Is(err, other)).To(BeFalse())
+					}
+				}
+			},
+			Entry("400", http.StatusBadRequest, aura.ErrValidation),
+			Entry("401", http.StatusUnauthorized, aura.ErrUnauthorized),
+			Entry("403", http.StatusForbidden, aura.ErrForbidden),
+			Entry("404", http.StatusNotFound, aura.ErrNotFound),
+			Entry("409", http.StatusConflict, aura.ErrConflict),
+			Entry("422", http.StatusUnprocessableEntity, aura.ErrValidation),
+			Entry("429", http.StatusTooManyRequests, aura.ErrRateLimited),
+			Entry("501", http.StatusNotImplemented, aura.ErrServer),
+		)
+		It("should match validation errors detected locally", func() {
+			client, err = aura.NewClient(context.Background(), "foo", "bar", "tenant-1",
+				aura.WithEndpoint(server.URL),
+				aura.WithConfigValidation())
+			Expect(err).To(Succeed())
+			responseMap[GET_TENANT] = mockJSON(http.StatusOK, map[string]any{
+				"data": mockedTenant("tenant-1"),
+			})
+			_, err := client.CreateInstance("foo", "gcp", "1TB", "5", "europe-west1", "enterprise-db")
+			Expect(err).To(MatchError(aura.ErrValidation))
+		})
+	})
+	Describe("Rate limiting", func() {
+		// throttle responds with 429 the given number of times before succeeding
+		throttle := func(times int, retryAfter string, then F) F {
+			return func(w http.ResponseWriter, r *http.Request) error {
+				if times == 0 {
+					return then(w, r)
+				}
+				times--
+				w.Header().Set("Retry-After", retryAfter)
+				return mockError(http.StatusTooManyRequests)(w, r)
+			}
+		}
+		It("should retry throttled requests after the time given by Retry-After", func() {
+			c, err := aura.NewClient(context.Background(), "foo", "bar", "mox", aura.WithEndpoint(server.URL))
+			Expect(err).To(Succeed())
+			mockGet("abc123")
+			responseMap[GET_INSTANCE] = throttle(2, "0", responseMap[GET_INSTANCE])
+			actual, err := c.GetInstance("abc123")
+			Expect(err).To(Succeed())
+			Expect(actual.Data.ID).To(Equal("abc123"))
+			Expect(callCounter[GET_INSTANCE]).To(Equal(3))
+			Expect(c.RateLimitStats().Throttled).To(Equal(int64(2)))
+			Expect(c.RateLimitStats().Requests).To(Equal(int64(3)))
+		})
+		It("should accept Retry-After as a date", func() {
+			mockGet("abc123")
+			past := time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat)
+			responseMap[GET_INSTANCE] = throttle(1, past, responseMap[GET_INSTANCE])
+			_, err := client.GetInstance("abc123")
+			Expect(err).To(Succeed())
+			Expect(callCounter[GET_INSTANCE]).To(Equal(2))
+		})
+		It("should send the body again when retrying", func() {
+			var bodies []string
+			create := mockJSON(http.StatusAccepted, map[string]any{
+				"data": map[string]any{"id": "db1d1234", "name": "foo"},
+			})
+			route := throttle(1, "0", create)
+			responseMap[CREATE_INSTANCE] = func(w http.ResponseWriter, r *http.Request) error {
+				var body map[string]any
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					return err
+				}
+				bodies = append(bodies, body["name"].(string))
+				return route(w, r)
+			}
+			_, err := client.CreateInstance("foo", "gcp", "8GB", "5", "europe-west1", "enterprise-db")
+			Expect(err).To(Succeed())
+			Expect(bodies).To(Equal([]string{"foo", "foo"}))
+		})
+		It("should give up after the configured number of retries", func() {
+			client, err = aura.NewClient(context.Background(), "foo", "bar", "mox",
+				aura.WithEndpoint(server.URL),
+				aura.WithRateLimitRetries(1))
+			Expect(err).To(Succeed())
+			responseMap[GET_INSTANCE] = throttle(5, "0", nil)
+			_, err := client.GetInstance("abc123")
+			Expect(err).To(MatchError(aura.ErrRateLimited))
+			Expect(callCounter[GET_INSTANCE]).To(Equal(2))
+		})
+		It("should not be retried by the retries for server errors", func() {
+			client, err = aura.NewClient(context.Background(), "foo", "bar", "mox",
+				aura.WithEndpoint(server.URL),
+				aura.WithRetries(3),
+				aura.WithRateLimitRetries(0))
+			Expect(err).To(Succeed())
+			responseMap[GET_INSTANCE] = throttle(5, "0", nil)
+			_, err := client.GetInstance("abc123")
+			Expect(err).To(MatchError(aura.ErrRateLimited))
+			Expect(callCounter[GET_INSTANCE]).To(Equal(1))
+		})
+		It("should not wait longer than the maximum", func() {
+			future := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+			for _, retryAfter := range []string{"3600", future} {
+				responseMap[GET_INSTANCE] = throttle(1, retryAfter, nil)
+				start := time.Now()
+				_, err := client.GetInstance("abc123")
+				Expect(err).To(MatchError(aura.ErrRateLimited))
+				Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+			}
+			Expect(callCounter[GET_INSTANCE]).To(Equal(2))
+
+			client, err = aura.NewClient(context.Background(), "foo", "bar", "mox",
+				aura.WithEndpoint(server.URL),
+				aura.WithMaxRetryAfter(time.Second))
+			Expect(err).To(Succeed())
+			responseMap[GET_INSTANCE] = throttle(1, "2", nil)
+			_, err := client.GetInstance("abc123")
+			Expect(err).To(MatchError(aura.ErrRateLimited))
+			Expect(callCounter[GET_INSTANCE]).To(Equal(3))
+		})
+		It("should stop waiting when the context is done", func() {
+			responseMap[GET_INSTANCE] = throttle(5, "60", nil)
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			start := time.Now()
+			_, err := client.GetInstanceWithContext(ctx, "abc123")
+			Expect(err).To(MatchError(context.DeadlineExceeded))
+			Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+		})
+		It("should hold back requests exceeding the client-side limit", func() {
+			c, err := aura.NewClient(context.Background(), "foo", "bar", "mox",
+				aura.WithEndpoint(server.URL),
+				aura.WithRateLimit(20, 1))
+			Expect(err).To(Succeed())
+			mockGet("abc123")
+			start := time.Now()
+			var wg sync.WaitGroup
+			for i := 0; i < 4; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					defer GinkgoRecover()
+					_, err := c.GetInstance("abc123")
+					Expect(err).To(Succeed())
+				}()
+			}
+			wg.Wait()
+			// One request is allowed right away and the rest are spread 50ms apart
+			Expect(time.Since(start)).To(BeNumerically(">=", 140*time.Millisecond))
+			stats := c.RateLimitStats()
+			Expect(stats.Requests).To(Equal(int64(4)))
+			Expect(stats.Delayed).To(Equal(int64(3)))
+			Expect(stats.LimiterWait).To(BeNumerically(">=", 140*time.Millisecond))
+			Expect(stats.MaxLimiterWait).To(BeNumerically(">=", 140*time.Millisecond))
+		})
+	})
+	Describe("Retry policies", func() {
+		fast := func(p *aura.StandardRetryPolicy) *aura.StandardRetryPolicy {
+			p.MinWait = time.Millisecond
+			p.MaxWait = 2 * time.Millisecond
+			return p
+		}
+		It("should retry the statuses of the policy", func() {
+			client, err = aura.NewClient(context.Background(), "foo", "bar", "mox",
+				aura.WithEndpoint(server.URL),
+				aura.WithRetryPolicy(fast(&aura.StandardRetryPolicy{
+					Retries:  2,
+					Statuses: []int{http.StatusServiceUnavailable},
+				})))
+			Expect(err).To(Succeed())
+			responseMap[GET_INSTANCE] = mockError(http.StatusServiceUnavailable)
+			_, err := client.GetInstance("abc123")
+			Expect(err).To(MatchError(aura.ErrServer))
+			Expect(callCounter[GET_INSTANCE]).To(Equal(3))
+
+			responseMap[GET_INSTANCE] = mockError(http.StatusInternalServerError)
+			_, err = client.GetInstance("abc123")
+			Expect(err).To(MatchError(aura.ErrServer))
+			Expect(callCounter[GET_INSTANCE]).To(Equal(4))
+		})
+		It("should never retry creating instances when only retrying idempotent requests", func() {
+			client, err = aura.NewClient(context.Background(), "foo", "bar", "mox",
+				aura.WithEndpoint(server.URL),
+				aura.WithRetryPolicy(fast(aura.IdempotentRetryPolicy(2))))
+			Expect(err).To(Succeed())
+			responseMap[CREATE_INSTANCE] = mockError(http.StatusInternalServerError)
+			_, err := client.CreateInstance("foo", "gcp", "8GB", "5", "europe-west1", "enterprise-db")
+			Expect(err).To(MatchError(aura.ErrServer))
+			Expect(callCounter[CREATE_INSTANCE]).To(Equal(1))
+
+			responseMap[GET_INSTANCE] = mockError(http.StatusInternalServerError)
+			_, err = client.GetInstance("abc123")
+			Expect(err).To(MatchError(aura.ErrServer))
+			Expect(callCounter[GET_INSTANCE]).To(Equal(3))
+		})
+		It("should not retry at all using NoRetryPolicy", func() {
+			client, err = aura.NewClient(context.Background(), "foo", "bar", "mox",
+				aura.WithEndpoint(server.URL),
+				aura.WithRetries(3),
+				aura.WithRetryPolicy(aura.NoRetryPolicy()))
+			Expect(err).To(Succeed())
+			responseMap[GET_INSTANCE] = mockError(http.StatusInternalServerError)
+			_, err := client.GetInstance("abc123")
+			Expect(err).NotTo(Succeed())
+			Expect(callCounter[GET_INSTANCE]).To(Equal(1))
+		})
+		It("should accept custom policies", func() {
+			policy := &recordingPolicy{}
+			client, err = aura.NewClient(context.Background(), "foo", "bar", "mox",
+				aura.WithEndpoint(server.URL),
+				aura.WithRetryPolicy(policy))
+			Expect(err).To(Succeed())
+			responseMap[DESTROY_INSTANCE] = mockError(http.StatusBadRequest)
+			err := client.DestroyInstance("abc123")
+			Expect(err).To(MatchError(aura.ErrValidation))
+			Expect(callCounter[DESTROY_INSTANCE]).To(Equal(2))
+			Expect(policy.methods).To(Equal([]string{"DELETE", "DELETE"}))
+		})
+		It("should log each retry", func() {
+			var b bytes.Buffer
+			client, err = aura.NewClient(context.Background(), "foo", "bar", "mox",
+				aura.WithEndpoint(server.URL),
+				aura.WithLogger(slog.New(slog.NewTextHandler(&b, nil))),
+				aura.WithRetryPolicy(fast(aura.DefaultRetryPolicy(2))))
+			Expect(err).To(Succeed())
+			responseMap[GET_INSTANCE] = mockError(http.StatusBadGateway)
+			_, err := client.GetInstance("abc123")
+			Expect(err).NotTo(Succeed())
+			Expect(b.String()).To(ContainSubstring("attempt=1"))
+			Expect(b.String()).To(ContainSubstring("attempt=2"))
+			Expect(b.String()).To(ContainSubstring("/v1/instances/abc123"))
+		})
+		It("should back off exponentially with jitter", func() {
+			p := aura.DefaultRetryPolicy(3)
+			Expect(p.Backoff(1, nil)).To(BeNumerically("~", time.Second, 100*time.Millisecond))
+			Expect(p.Backoff(3, nil)).To(BeNumerically("~", 4*time.Second, 400*time.Millisecond))
+			Expect(p.Backoff(40, nil)).To(BeNumerically("~", 30*time.Second, 3*time.Second))
+			unavailable := &http.Response{
+				StatusCode: http.StatusServiceUnavailable,
+				Header:     http.Header{"Retry-After": []string{"7"}},
+			}
+			Expect(p.Backoff(1, unavailable)).To(Equal(7 * time.Second))
+		})
+		It("should not wait longer than the maximum when Aura is unavailable", func() {
+			p := aura.DefaultRetryPolicy(3)
+			for _, retryAfter := range []string{"86400", time.Now().Add(24 * time.Hour).UTC().Format(http.TimeFormat)} {
+				unavailable := &http.Response{
+					StatusCode: http.StatusServiceUnavailable,
+					Header:     http.Header{"Retry-After": []string{retryAfter}},
+				}
+				Expect(p.Backoff(1, unavailable)).To(Equal(30 * time.Second))
+			}
+		})
+	})
+	Describe("Idempotent creation", func() {
+		var existing []any
+		production := map[string]any{
+			"id":             "abc123",
+			"name":           "Production",
+			"tenant_id":      "YOUR_TENANT_ID",
+			"cloud_provider": "gcp",
+			"region":         "europe-west1",
+			"type":           "enterprise-db",
+		}
+		BeforeEach(func() {
+			existing = []any{}
+			client, err = aura.NewClient(context.Background(), "foo", "bar", "YOUR_TENANT_ID",
+				aura.WithEndpoint(server.URL),
+				aura.WithRetries(2),
+				aura.WithIdempotentCreate())
+			Expect(err).To(Succeed())
+			responseMap[LIST_INSTANCES] = func(w http.ResponseWriter, r *http.Request) error {
+				return mockJSON(http.StatusOK, map[string]any{"data": existing})(w, r)
+			}
+			mockGet("abc123")
+			responseMap[CREATE_INSTANCE] = mockJSON(http.StatusAccepted, map[string]any{
+				"data": map[string]any{"id": "new456", "name": "Production", "password": "secret"},
+			})
+		})
+		It("should create the instance when the tenant does not have it", func() {
+			actual, err := client.CreateInstance("Production", "gcp", "8GB", "5", "europe-west1", "enterprise-db")
+			Expect(err).To(Succeed())
+			Expect(actual.Data.ID).To(Equal("new456"))
+			Expect(callCounter[LIST_INSTANCES]).To(Equal(1))
+			Expect(callCounter[CREATE_INSTANCE]).To(Equal(1))
+		})
+		It("should return the existing instance with the same name and configuration", func() {
+			existing = []any{production}
+			actual, err := client.CreateInstance("Production", "gcp", "8GB", "5", "europe-west1", "enterprise-db")
+			Expect(err).To(Succeed())
+			Expect(actual.Data.ID).To(Equal("abc123"))
+			Expect(actual.Data.Password).To(BeEmpty())
+			Expect(callCounter[CREATE_INSTANCE]).To(Equal(0))
+		})
+		It("should report a conflict when the existing instance has another configuration", func() {
+			existing = []any{production}
+			_, err := client.CreateInstance("Production", "gcp", "16GB", "5", "europe-west1", "enterprise-db")
+			var conflict *aura.InstanceConflictError
+			Expect(errors.As(err, &conflict)).To(BeTrue())
+			Expect(conflict.ID).To(Equal("abc123"))
+			Expect(conflict.Existing.Memory).To(Equal("8GB"))
+			Expect(err).To(MatchError(aura.ErrConflict))
+			Expect(err.Error()).To(ContainSubstring(`memory is "8GB", not "16GB"`))
+			Expect(callCounter[CREATE_INSTANCE]).To(Equal(0))
+		})
+		It("should ignore instances being destroyed", func() {
+			existing = []any{production}
+			mockStatuses("abc123", aura.StatusDestroying)
+			actual, err := client.CreateInstance("Production", "gcp", "8GB", "5", "europe-west1", "enterprise-db")
+			Expect(err).To(Succeed())
+			Expect(actual.Data.ID).To(Equal("new456"))
+		})
+		It("should return the instance created by a request that failed", func() {
+			responseMap[CREATE_INSTANCE] = func(w http.ResponseWriter, r *http.Request) error {
+				existing = []any{production}
+				return mockError(http.StatusBadGateway)(w, r)
+			}
+			actual, err := client.CreateInstance("Production", "gcp", "8GB", "5", "europe-west1", "enterprise-db")
+			Expect(err).To(Succeed())
+			Expect(actual.Data.ID).To(Equal("abc123"))
+			Expect(callCounter[CREATE_INSTANCE]).To(Equal(1))
+			Expect(callCounter[LIST_INSTANCES]).To(Equal(2))
+		})
+		It("should not retry creating the instance", func() {
+			responseMap[CREATE_INSTANCE] = mockError(http.StatusBadGateway)
+			_, err := client.CreateInstance("Production", "gcp", "8GB", "5", "europe-west1", "enterprise-db")
+			Expect(err).To(MatchError(aura.ErrServer))
+			Expect(callCounter[CREATE_INSTANCE]).To(Equal(1))
+			Expect(callCounter[LIST_INSTANCES]).To(Equal(2))
+		})
+		It("should not look for the instance when the request was rejected", func() {
+			responseMap[CREATE_INSTANCE] = mockError(http.StatusBadRequest)
+			_, err := client.CreateInstance("Production", "gcp", "8GB", "5", "europe-west1", "enterprise-db")
+			Expect(err).To(MatchError(aura.ErrValidation))
+			Expect(callCounter[LIST_INSTANCES]).To(Equal(1))
+		})
+	})
+	Describe("Telemetry", func() {
+		var (
+			spans  *tracetest.SpanRecorder
+			reader *sdkmetric.ManualReader
+		)
+		attributes := func(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+			m := make(map[attribute.Key]attribute.Value)
+			for _, kv := range span.Attributes() {
+				m[kv.Key] = kv.Value
+			}
+			return m
+		}
+		// operations returns the outcomes of the recorded operations by operation and outcome
+		operations := func() map[string]int64 {
+			var rm metricdata.ResourceMetrics
+			Expect(reader.Collect(context.Background(), &rm)).To(Succeed())
+			counts := make(map[string]int64)
+			for _, sm := range rm.ScopeMetrics {
+				for _, m := range sm.Metrics {
+					switch data := m.Data.(type) {
+					case metricdata.Sum[int64]:
+						for _, dp := range data.DataPoints {
+							op, _ := dp.Attributes.Value(aura.AttributeOperation)
+							outcome, _ := dp.Attributes.Value(aura.AttributeOutcome)
+							counts[m.Name+" "+op.AsString()+" "+outcome.AsString()] += dp.Value
+						}
+					case metricdata.Histogram[float64]:
+						for _, dp := range data.DataPoints {
+							op, _ := dp.Attributes.Value(aura.AttributeOperation)
+							outcome, _ := dp.Attributes.Value(aura.AttributeOutcome)
+							counts[m.Name+" "+op.AsString()+" "+outcome.AsString()] += int64(dp.Count)
+						}
+					}
+				}
+			}
+			return counts
+		}
+		BeforeEach(func() {
+			spans = tracetest.NewSpanRecorder()
+			reader = sdkmetric.NewManualReader()
+			client, err = aura.NewClient(context.Background(), "foo", "bar", "mox",
+				aura.WithEndpoint(server.URL),
+				aura.WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))),
+				aura.WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+				aura.WithRetryPolicy(&aura.StandardRetryPolicy{
+					Retries:  2,
+					Statuses: []int{http.StatusBadGateway},
+					MinWait:  time.Millisecond,
+					MaxWait:  time.Millisecond,
+				}))
+			Expect(err).To(Succeed())
+		})
+		It("should create a span for each operation", func() {
+			mockGet("abc123")
+			_, err := client.GetInstance("abc123")
+			Expect(err).To(Succeed())
+			Expect(spans.Ended()).To(HaveLen(1))
+			span := spans.Ended()[0]
+			Expect(span.Name()).To(Equal("aura.GetInstance"))
+			Expect(span.Status().Code).To(Equal(codes.Unset))
+			Expect(attributes(span)).To(Equal(map[attribute.Key]attribute.Value{
+				aura.AttributeInstanceID: attribute.StringValue("abc123"),
+				aura.AttributeStatusCode: attribute.IntValue(http.StatusOK),
+				aura.AttributeRequestID:  attribute.StringValue(responseId),
+				aura.AttributeRetryCount: attribute.IntValue(0),
+			}))
+		})
+		It("should nest the requests of an operation", func() {
+			mockStatuses("abc123", aura.StatusCreating, aura.StatusRunning)
+			_, err := client.WaitForStatus(context.Background(), "abc123", []string{aura.StatusRunning},
+				aura.WithPollInterval(time.Millisecond, time.Millisecond))
+			Expect(err).To(Succeed())
+			ended := spans.Ended()
+			Expect(ended).To(HaveLen(3))
+			wait := ended[2]
+			Expect(wait.Name()).To(Equal("aura.WaitForStatus"))
+			Expect(ended[0].Parent().SpanID()).To(Equal(wait.SpanContext().SpanID()))
+			Expect(ended[1].Parent().SpanID()).To(Equal(wait.SpanContext().SpanID()))
+		})
+		It("should record failures and retries", func() {
+			responseMap[PAUSE_INSTANCE] = mockError(http.StatusBadGateway)
+			err := client.PauseInstance("abc123")
+			Expect(err).To(MatchError(aura.ErrServer))
+			Expect(callCounter[PAUSE_INSTANCE]).To(Equal(3))
+			span := spans.Ended()[0]
+			Expect(span.Status().Code).To(Equal(codes.Error))
+			Expect(span.Events()).To(ContainElement(HaveField("Name", "exception")))
+			Expect(attributes(span)).To(HaveKeyWithValue(aura.AttributeRetryCount, attribute.IntValue(2)))
+			Expect(attributes(span)).To(HaveKeyWithValue(aura.AttributeStatusCode, attribute.IntValue(502)))
+			Expect(attributes(span)).To(HaveKeyWithValue(aura.AttributeRequestID, attribute.StringValue(responseId)))
+
+			mockGet("abc123")
+			_, err = client.GetInstance("abc123")
+			Expect(err).To(Succeed())
+			Expect(operations()).To(Equal(map[string]int64{
+				aura.MetricOperationDuration + " aura.PauseInstance server_error": 1,
+				aura.MetricOperations + " aura.PauseInstance server_error":        1,
+				aura.MetricRetries + " aura.PauseInstance ":                       2,
+				aura.MetricOperationDuration + " aura.GetInstance ok":             1,
+				aura.MetricOperations + " aura.GetInstance ok":                    1,
+			}))
+		})
+		It("should add the ID of created instances", func() {
+			responseMap[CREATE_INSTANCE] = mockJSON(http.StatusAccepted, map[string]any{
+				"data": map[string]any{"id": "new456", "name": "Production"},
+			})
+			_, err := client.CreateInstance("Production", "gcp", "8GB", "5", "europe-west1", "enterprise-db")
+			Expect(err).To(Succeed())
+			span := spans.Ended()[0]
+			Expect(span.Name()).To(Equal("aura.CreateInstance"))
+			Expect(attributes(span)).To(HaveKeyWithValue(aura.AttributeInstanceID, attribute.StringValue("new456")))
+			Expect(attributes(span)).To(HaveKeyWithValue(aura.AttributeTenantID, attribute.StringValue("mox")))
+		})
+	})
 })
@@ -0,0 +1,13 @@
+package auratest_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestAuratest(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Auratest Suite")
+}
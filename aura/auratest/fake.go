@@ -0,0 +1,531 @@
+package auratest
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/indykite/aura-api-client/aura"
+)
+
+// FakeClient is an aura.Client for unit tests, which records the calls made to
+// it and answers them using the function set for each method. Methods without
+// a function return an empty response and no error. Methods are recorded and
+// answered the same whether or not they were called with a context, so calling
+// GetInstance is recorded as a call to "GetInstance" and answered by GetInstanceFunc.
+type FakeClient struct {
+	ListInstancesFunc  func(ctx context.Context, tenantID string) (*aura.ListResponse, error)
+	CreateInstanceFunc func(
+		ctx context.Context, name, cloudProvider, memory, version, region, instanceType string,
+	) (*aura.CreateResponse, error)
+	CreateInstanceFromRequestFunc func(ctx context.Context, r aura.CreateInstanceRequest) (*aura.CreateResponse, error)
+	GetInstanceFunc               func(ctx context.Context, id string) (*aura.GetResponse, error)
+	DestroyInstanceFunc           func(ctx context.Context, id string) error
+	PauseInstanceFunc             func(ctx context.Context, id string) error
+	ResumeInstanceFunc            func(ctx context.Context, id string) error
+	UpdateInstanceFunc            func(
+		ctx context.Context, id string, update aura.UpdateRequest,
+	) (*aura.GetResponse, error)
+	OverwriteInstanceFunc func(
+		ctx context.Context, targetID, sourceInstanceID, sourceSnapshotID string,
+	) (*aura.GetResponse, error)
+	ListSnapshotsFunc   func(ctx context.Context, instanceID, date string) (*aura.ListSnapshotsResponse, error)
+	CreateSnapshotFunc  func(ctx context.Context, instanceID string) (*aura.CreateSnapshotResponse, error)
+	GetSnapshotFunc     func(ctx context.Context, instanceID, snapshotID string) (*aura.GetSnapshotResponse, error)
+	RestoreSnapshotFunc func(ctx context.Context, instanceID, snapshotID string) (*aura.GetResponse, error)
+	ListTenantsFunc     func(ctx context.Context) (*aura.ListTenantsResponse, error)
+	GetTenantFunc       func(ctx context.Context, id string) (*aura.TenantResponse, error)
+	WaitForStatusFunc   func(
+		ctx context.Context, id string, targetStatuses []string, options ...aura.WaitOption,
+	) (*aura.GetResponse, error)
+	ListCustomerManagedKeysFunc func(
+		ctx context.Context, tenantID string,
+	) (*aura.ListCustomerManagedKeysResponse, error)
+	CreateCustomerManagedKeyFunc func(
+		ctx context.Context, r aura.CreateCustomerManagedKeyRequest,
+	) (*aura.CustomerManagedKeyResponse, error)
+	GetCustomerManagedKeyFunc     func(ctx context.Context, id string) (*aura.CustomerManagedKeyResponse, error)
+	DeleteCustomerManagedKeyFunc  func(ctx context.Context, id string) error
+	WaitForCustomerManagedKeyFunc func(
+		ctx context.Context, id string, options ...aura.WaitOption,
+	) (*aura.CustomerManagedKeyResponse, error)
+	EstimateSessionSizeFunc func(ctx context.Context, r aura.SessionSizeRequest) (*aura.SessionSizeResponse, error)
+	ListSessionsFunc        func(ctx context.Context, tenantID, instanceID string) (*aura.ListSessionsResponse, error)
+	CreateSessionFunc       func(ctx context.Context, r aura.CreateSessionRequest) (*aura.SessionResponse, error)
+	GetSessionFunc          func(ctx context.Context, id string) (*aura.SessionResponse, error)
+	DeleteSessionFunc       func(ctx context.Context, id string) error
+	WaitForSessionFunc      func(
+		ctx context.Context, id string, options ...aura.WaitOption,
+	) (*aura.SessionResponse, error)
+	GetMetricsIntegrationFunc func(
+		ctx context.Context, tenantID string,
+	) (*aura.MetricsIntegrationResponse, error)
+	ScrapeMetricsFunc func(ctx context.Context, endpoint string) (*aura.Metrics, error)
+
+	mu    sync.Mutex
+	calls []Call
+}
+
+var _ aura.Client = (*FakeClient)(nil)
+
+// Call is a call made to a FakeClient.
+type Call struct {
+	Method string // Name of the method without the WithContext suffix, i.e. "DestroyInstance"
+	Args   []any  // Arguments other than the context and wait options
+}
+
+func (c Call) String() string {
+	return fmt.Sprintf("%s%v", c.Method, c.Args)
+}
+
+func (f *FakeClient) record(method string, args ...any) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, Call{Method: method, Args: args})
+}
+
+// Calls returns the calls made so far, in order.
+func (f *FakeClient) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+// CallsTo returns the calls made so far to the given method. Only the calls with
+// the given arguments are returned, unless no arguments are given.
+func (f *FakeClient) CallsTo(method string, args ...any) []Call {
+	var calls []Call
+	for _, c := range f.Calls() {
+		if c.Method == method && (len(args) == 0 || reflect.DeepEqual(c.Args, args)) {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// Reset forgets the calls made so far, keeping the functions.
+func (f *FakeClient) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = nil
+}
+
+// AssertCalled fails the test unless the method was called with the given
+// arguments, or at all if no arguments are given.
+func (f *FakeClient) AssertCalled(t testing.TB, method string, args ...any) {
+	t.Helper()
+	if len(f.CallsTo(method, args...)) == 0 {
+		t.Errorf("expected a call to %s, got calls %v", Call{Method: method, Args: args}, f.Calls())
+	}
+}
+
+// AssertNotCalled fails the test if the method was called with the given
+// arguments, or at all if no arguments are given.
+func (f *FakeClient) AssertNotCalled(t testing.TB, method string, args ...any) {
+	t.Helper()
+	if calls := f.CallsTo(method, args...); len(calls) > 0 {
+		t.Errorf("expected no call to %s, got calls %v", Call{Method: method, Args: args}, calls)
+	}
+}
+
+// AssertCalledTimes fails the test unless the method was called with the given
+// arguments exactly n times, i.e. AssertCalledTimes(t, 1, "DestroyInstance", id).
+func (f *FakeClient) AssertCalledTimes(t testing.TB, n int, method string, args ...any) {
+	t.Helper()
+	if calls := f.CallsTo(method, args...); len(calls) != n {
+		t.Errorf("expected %d calls to %s, got %d in calls %v", n, Call{Method: method, Args: args}, len(calls), f.Calls())
+	}
+}
+
+func (f *FakeClient) ListInstances(tenantID string) (*aura.ListResponse, error) {
+	return f.ListInstancesWithContext(context.Background(), tenantID)
+}
+
+func (f *FakeClient) ListInstancesWithContext(ctx context.Context, tenantID string) (*aura.ListResponse, error) {
+	f.record("ListInstances", tenantID)
+	if f.ListInstancesFunc != nil {
+		return f.ListInstancesFunc(ctx, tenantID)
+	}
+	return &aura.ListResponse{}, nil
+}
+
+func (f *FakeClient) CreateInstance(
+	name, cloudProvider, memory, version, region, instanceType string,
+) (*aura.CreateResponse, error) {
+	return f.CreateInstanceWithContext(context.Background(), name, cloudProvider, memory, version, region, instanceType)
+}
+
+func (f *FakeClient) CreateInstanceWithContext(
+	ctx context.Context, name, cloudProvider, memory, version, region, instanceType string,
+) (*aura.CreateResponse, error) {
+	f.record("CreateInstance", name, cloudProvider, memory, version, region, instanceType)
+	if f.CreateInstanceFunc != nil {
+		return f.CreateInstanceFunc(ctx, name, cloudProvider, memory, version, region, instanceType)
+	}
+	return &aura.CreateResponse{}, nil
+}
+
+func (f *FakeClient) CreateInstanceFromRequest(r aura.CreateInstanceRequest) (*aura.CreateResponse, error) {
+	return f.CreateInstanceFromRequestWithContext(context.Background(), r)
+}
+
+func (f *FakeClient) CreateInstanceFromRequestWithContext(
+	ctx context.Context, r aura.CreateInstanceRequest,
+) (*aura.CreateResponse, error) {
+	f.record("CreateInstanceFromRequest", r)
+	if f.CreateInstanceFromRequestFunc != nil {
+		return f.CreateInstanceFromRequestFunc(ctx, r)
+	}
+	return &aura.CreateResponse{}, nil
+}
+
+func (f *FakeClient) GetInstance(id string) (*aura.GetResponse, error) {
+	return f.GetInstanceWithContext(context.Background(), id)
+}
+
+func (f *FakeClient) GetInstanceWithContext(ctx context.Context, id string) (*aura.GetResponse, error) {
+	f.record("GetInstance", id)
+	if f.GetInstanceFunc != nil {
+		return f.GetInstanceFunc(ctx, id)
+	}
+	return &aura.GetResponse{}, nil
+}
+
+func (f *FakeClient) DestroyInstance(id string) error {
+	return f.DestroyInstanceWithContext(context.Background(), id)
+}
+
+func (f *FakeClient) DestroyInstanceWithContext(ctx context.Context, id string) error {
+	f.record("DestroyInstance", id)
+	if f.DestroyInstanceFunc != nil {
+		return f.DestroyInstanceFunc(ctx, id)
+	}
+	return nil
+}
+
+func (f *FakeClient) PauseInstance(id string) error {
+	return f.PauseInstanceWithContext(context.Background(), id)
+}
+
+func (f *FakeClient) PauseInstanceWithContext(ctx context.Context, id string) error {
+	f.record("PauseInstance", id)
+	if f.PauseInstanceFunc != nil {
+		return f.PauseInstanceFunc(ctx, id)
+	}
+	return nil
+}
+
+func (f *FakeClient) ResumeInstance(id string) error {
+	return f.ResumeInstanceWithContext(context.Background(), id)
+}
+
+func (f *FakeClient) ResumeInstanceWithContext(ctx context.Context, id string) error {
+	f.record("ResumeInstance", id)
+	if f.ResumeInstanceFunc != nil {
+		return f.ResumeInstanceFunc(ctx, id)
+	}
+	return nil
+}
+
+func (f *FakeClient) UpdateInstance(id string, update aura.UpdateRequest) (*aura.GetResponse, error) {
+	return f.UpdateInstanceWithContext(context.Background(), id, update)
+}
+
+func (f *FakeClient) UpdateInstanceWithContext(
+	ctx context.Context, id string, update aura.UpdateRequest,
+) (*aura.GetResponse, error) {
+	f.record("UpdateInstance", id, update)
+	if f.UpdateInstanceFunc != nil {
+		return f.UpdateInstanceFunc(ctx, id, update)
+	}
+	return &aura.GetResponse{}, nil
+}
+
+func (f *FakeClient) OverwriteInstance(targetID, sourceInstanceID, sourceSnapshotID string) (*aura.GetResponse, error) {
+	return f.OverwriteInstanceWithContext(context.Background(), targetID, sourceInstanceID, sourceSnapshotID)
+}
+
+func (f *FakeClient) OverwriteInstanceWithContext(
+	ctx context.Context, targetID, sourceInstanceID, sourceSnapshotID string,
+) (*aura.GetResponse, error) {
+	f.record("OverwriteInstance", targetID, sourceInstanceID, sourceSnapshotID)
+	if f.OverwriteInstanceFunc != nil {
+		return f.OverwriteInstanceFunc(ctx, targetID, sourceInstanceID, sourceSnapshotID)
+	}
+	return &aura.GetResponse{}, nil
+}
+
+func (f *FakeClient) ListSnapshots(instanceID, date string) (*aura.ListSnapshotsResponse, error) {
+	return f.ListSnapshotsWithContext(context.Background(), instanceID, date)
+}
+
+func (f *FakeClient) ListSnapshotsWithContext(
+	ctx context.Context, instanceID, date string,
+) (*aura.ListSnapshotsResponse, error) {
+	f.record("ListSnapshots", instanceID, date)
+	if f.ListSnapshotsFunc != nil {
+		return f.ListSnapshotsFunc(ctx, instanceID, date)
+	}
+	return &aura.ListSnapshotsResponse{}, nil
+}
+
+func (f *FakeClient) CreateSnapshot(instanceID string) (*aura.CreateSnapshotResponse, error) {
+	return f.CreateSnapshotWithContext(context.Background(), instanceID)
+}
+
+func (f *FakeClient) CreateSnapshotWithContext(
+	ctx context.Context, instanceID string,
+) (*aura.CreateSnapshotResponse, error) {
+	f.record("CreateSnapshot", instanceID)
+	if f.CreateSnapshotFunc != nil {
+		return f.CreateSnapshotFunc(ctx, instanceID)
+	}
+	return &aura.CreateSnapshotResponse{}, nil
+}
+
+func (f *FakeClient) GetSnapshot(instanceID, snapshotID string) (*aura.GetSnapshotResponse, error) {
+	return f.GetSnapshotWithContext(context.Background(), instanceID, snapshotID)
+}
+
+func (f *FakeClient) GetSnapshotWithContext(
+	ctx context.Context, instanceID, snapshotID string,
+) (*aura.GetSnapshotResponse, error) {
+	f.record("GetSnapshot", instanceID, snapshotID)
+	if f.GetSnapshotFunc != nil {
+		return f.GetSnapshotFunc(ctx, instanceID, snapshotID)
+	}
+	return &aura.GetSnapshotResponse{}, nil
+}
+
+func (f *FakeClient) RestoreSnapshot(instanceID, snapshotID string) (*aura.GetResponse, error) {
+	return f.RestoreSnapshotWithContext(context.Background(), instanceID, snapshotID)
+}
+
+func (f *FakeClient) RestoreSnapshotWithContext(
+	ctx context.Context, instanceID, snapshotID string,
+) (*aura.GetResponse, error) {
+	f.record("RestoreSnapshot", instanceID, snapshotID)
+	if f.RestoreSnapshotFunc != nil {
+		return f.RestoreSnapshotFunc(ctx, instanceID, snapshotID)
+	}
+	return &aura.GetResponse{}, nil
+}
+
+func (f *FakeClient) ListTenants() (*aura.ListTenantsResponse, error) {
+	return f.ListTenantsWithContext(context.Background())
+}
+
+func (f *FakeClient) ListTenantsWithContext(ctx context.Context) (*aura.ListTenantsResponse, error) {
+	f.record("ListTenants")
+	if f.ListTenantsFunc != nil {
+		return f.ListTenantsFunc(ctx)
+	}
+	return &aura.ListTenantsResponse{}, nil
+}
+
+func (f *FakeClient) GetTenant(id string) (*aura.TenantResponse, error) {
+	return f.GetTenantWithContext(context.Background(), id)
+}
+
+func (f *FakeClient) GetTenantWithContext(ctx context.Context, id string) (*aura.TenantResponse, error) {
+	f.record("GetTenant", id)
+	if f.GetTenantFunc != nil {
+		return f.GetTenantFunc(ctx, id)
+	}
+	return &aura.TenantResponse{}, nil
+}
+
+// WaitForStatus returns a response with the first of the target statuses
+// unless WaitForStatusFunc is set.
+func (f *FakeClient) WaitForStatus(
+	ctx context.Context, id string, targetStatuses []string, options ...aura.WaitOption,
+) (*aura.GetResponse, error) {
+	f.record("WaitForStatus", id, targetStatuses)
+	if f.WaitForStatusFunc != nil {
+		return f.WaitForStatusFunc(ctx, id, targetStatuses, options...)
+	}
+	resp := &aura.GetResponse{}
+	resp.Data.ID = id
+	if len(targetStatuses) > 0 {
+		resp.Data.Status = targetStatuses[0]
+	}
+	return resp, nil
+}
+
+func (f *FakeClient) ListCustomerManagedKeys(tenantID string) (*aura.ListCustomerManagedKeysResponse, error) {
+	return f.ListCustomerManagedKeysWithContext(context.Background(), tenantID)
+}
+
+func (f *FakeClient) ListCustomerManagedKeysWithContext(
+	ctx context.Context, tenantID string,
+) (*aura.ListCustomerManagedKeysResponse, error) {
+	f.record("ListCustomerManagedKeys", tenantID)
+	if f.ListCustomerManagedKeysFunc != nil {
+		return f.ListCustomerManagedKeysFunc(ctx, tenantID)
+	}
+	return &aura.ListCustomerManagedKeysResponse{}, nil
+}
+
+func (f *FakeClient) CreateCustomerManagedKey(
+	r aura.CreateCustomerManagedKeyRequest,
+) (*aura.CustomerManagedKeyResponse, error) {
+	return f.CreateCustomerManagedKeyWithContext(context.Background(), r)
+}
+
+func (f *FakeClient) CreateCustomerManagedKeyWithContext(
+	ctx context.Context, r aura.CreateCustomerManagedKeyRequest,
+) (*aura.CustomerManagedKeyResponse, error) {
+	f.record("CreateCustomerManagedKey", r)
+	if f.CreateCustomerManagedKeyFunc != nil {
+		return f.CreateCustomerManagedKeyFunc(ctx, r)
+	}
+	return &aura.CustomerManagedKeyResponse{}, nil
+}
+
+func (f *FakeClient) GetCustomerManagedKey(id string) (*aura.CustomerManagedKeyResponse, error) {
+	return f.GetCustomerManagedKeyWithContext(context.Background(), id)
+}
+
+func (f *FakeClient) GetCustomerManagedKeyWithContext(
+	ctx context.Context, id string,
+) (*aura.CustomerManagedKeyResponse, error) {
+	f.record("GetCustomerManagedKey", id)
+	if f.GetCustomerManagedKeyFunc != nil {
+		return f.GetCustomerManagedKeyFunc(ctx, id)
+	}
+	return &aura.CustomerManagedKeyResponse{}, nil
+}
+
+func (f *FakeClient) DeleteCustomerManagedKey(id string) error {
+	return f.DeleteCustomerManagedKeyWithContext(context.Background(), id)
+}
+
+func (f *FakeClient) DeleteCustomerManagedKeyWithContext(ctx context.Context, id string) error {
+	f.record("DeleteCustomerManagedKey", id)
+	if f.DeleteCustomerManagedKeyFunc != nil {
+		return f.DeleteCustomerManagedKeyFunc(ctx, id)
+	}
+	return nil
+}
+
+// WaitForCustomerManagedKey returns a ready key unless WaitForCustomerManagedKeyFunc is set.
+func (f *FakeClient) WaitForCustomerManagedKey(
+	ctx context.Context, id string, options ...aura.WaitOption,
+) (*aura.CustomerManagedKeyResponse, error) {
+	f.record("WaitForCustomerManagedKey", id)
+	if f.WaitForCustomerManagedKeyFunc != nil {
+		return f.WaitForCustomerManagedKeyFunc(ctx, id, options...)
+	}
+	resp := &aura.CustomerManagedKeyResponse{}
+	resp.Data.ID = id
+	resp.Data.Status = aura.CustomerManagedKeyStatusReady
+	return resp, nil
+}
+
+func (f *FakeClient) EstimateSessionSize(r aura.SessionSizeRequest) (*aura.SessionSizeResponse, error) {
+	return f.EstimateSessionSizeWithContext(context.Background(), r)
+}
+
+func (f *FakeClient) EstimateSessionSizeWithContext(
+	ctx context.Context, r aura.SessionSizeRequest,
+) (*aura.SessionSizeResponse, error) {
+	f.record("EstimateSessionSize", r)
+	if f.EstimateSessionSizeFunc != nil {
+		return f.EstimateSessionSizeFunc(ctx, r)
+	}
+	return &aura.SessionSizeResponse{}, nil
+}
+
+func (f *FakeClient) ListSessions(tenantID, instanceID string) (*aura.ListSessionsResponse, error) {
+	return f.ListSessionsWithContext(context.Background(), tenantID, instanceID)
+}
+
+func (f *FakeClient) ListSessionsWithContext(
+	ctx context.Context, tenantID, instanceID string,
+) (*aura.ListSessionsResponse, error) {
+	f.record("ListSessions", tenantID, instanceID)
+	if f.ListSessionsFunc != nil {
+		return f.ListSessionsFunc(ctx, tenantID, instanceID)
+	}
+	return &aura.ListSessionsResponse{}, nil
+}
+
+func (f *FakeClient) CreateSession(r aura.CreateSessionRequest) (*aura.SessionResponse, error) {
+	return f.CreateSessionWithContext(context.Background(), r)
+}
+
+func (f *FakeClient) CreateSessionWithContext(
+	ctx context.Context, r aura.CreateSessionRequest,
+) (*aura.SessionResponse, error) {
+	f.record("CreateSession", r)
+	if f.CreateSessionFunc != nil {
+		return f.CreateSessionFunc(ctx, r)
+	}
+	return &aura.SessionResponse{}, nil
+}
+
+func (f *FakeClient) GetSession(id string) (*aura.SessionResponse, error) {
+	return f.GetSessionWithContext(context.Background(), id)
+}
+
+func (f *FakeClient) GetSessionWithContext(ctx context.Context, id string) (*aura.SessionResponse, error) {
+	f.record("GetSession", id)
+	if f.GetSessionFunc != nil {
+		return f.GetSessionFunc(ctx, id)
+	}
+	return &aura.SessionResponse{}, nil
+}
+
+func (f *FakeClient) DeleteSession(id string) error {
+	return f.DeleteSessionWithContext(context.Background(), id)
+}
+
+func (f *FakeClient) DeleteSessionWithContext(ctx context.Context, id string) error {
+	f.record("DeleteSession", id)
+	if f.DeleteSessionFunc != nil {
+		return f.DeleteSessionFunc(ctx, id)
+	}
+	return nil
+}
+
+// WaitForSession returns a ready session unless WaitForSessionFunc is set.
+func (f *FakeClient) WaitForSession(
+	ctx context.Context, id string, options ...aura.WaitOption,
+) (*aura.SessionResponse, error) {
+	f.record("WaitForSession", id)
+	if f.WaitForSessionFunc != nil {
+		return f.WaitForSessionFunc(ctx, id, options...)
+	}
+	resp := &aura.SessionResponse{}
+	resp.Data.ID = id
+	resp.Data.Status = aura.SessionStatusReady
+	return resp, nil
+}
+
+func (f *FakeClient) GetMetricsIntegration(tenantID string) (*aura.MetricsIntegrationResponse, error) {
+	return f.GetMetricsIntegrationWithContext(context.Background(), tenantID)
+}
+
+func (f *FakeClient) GetMetricsIntegrationWithContext(
+	ctx context.Context, tenantID string,
+) (*aura.MetricsIntegrationResponse, error) {
+	f.record("GetMetricsIntegration", tenantID)
+	if f.GetMetricsIntegrationFunc != nil {
+		return f.GetMetricsIntegrationFunc(ctx, tenantID)
+	}
+	return &aura.MetricsIntegrationResponse{}, nil
+}
+
+func (f *FakeClient) ScrapeMetrics(endpoint string) (*aura.Metrics, error) {
+	return f.ScrapeMetricsWithContext(context.Background(), endpoint)
+}
+
+func (f *FakeClient) ScrapeMetricsWithContext(ctx context.Context, endpoint string) (*aura.Metrics, error) {
+	f.record("ScrapeMetrics", endpoint)
+	if f.ScrapeMetricsFunc != nil {
+		return f.ScrapeMetricsFunc(ctx, endpoint)
+	}
+	return &aura.Metrics{Instances: map[string]*aura.InstanceMetrics{}}, nil
+}
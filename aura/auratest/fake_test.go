@@ -0,0 +1,90 @@
+package auratest_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/indykite/aura-api-client/aura"
+	"github.com/indykite/aura-api-client/aura/auratest"
+)
+
+// recordingT records the errors of failed assertions.
+type recordingT struct {
+	testing.TB
+	errors []string
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Errorf(format string, args ...any) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+var _ = Describe("FakeClient", func() {
+	var (
+		fake *auratest.FakeClient
+		t    *recordingT
+	)
+	BeforeEach(func() {
+		fake = &auratest.FakeClient{}
+		t = &recordingT{}
+	})
+	It("should answer using the functions set", func() {
+		fake.GetInstanceFunc = func(ctx context.Context, id string) (*aura.GetResponse, error) {
+			if id == "missing" {
+				return nil, &aura.AuraError{StatusCode: 404}
+			}
+			resp := &aura.GetResponse{}
+			resp.Data.ID = id
+			return resp, nil
+		}
+		actual, err := fake.GetInstance("abc123")
+		Expect(err).To(Succeed())
+		Expect(actual.Data.ID).To(Equal("abc123"))
+		_, err = fake.GetInstanceWithContext(context.Background(), "missing")
+		Expect(err).To(MatchError(aura.ErrNotFound))
+	})
+	It("should answer with empty responses by default", func() {
+		list, err := fake.ListInstances("")
+		Expect(err).To(Succeed())
+		Expect(list.Data).To(BeEmpty())
+		Expect(fake.DestroyInstance("abc123")).To(Succeed())
+		resp, err := fake.WaitForStatus(context.Background(), "abc123", []string{aura.StatusRunning})
+		Expect(err).To(Succeed())
+		Expect(resp.Data.Status).To(Equal(aura.StatusRunning))
+	})
+	It("should record calls", func() {
+		_ = fake.DestroyInstance("abc123")
+		_ = fake.DestroyInstanceWithContext(context.Background(), "def456")
+		_, _ = fake.UpdateInstance("abc123", aura.UpdateRequest{Memory: "16GB"})
+		Expect(fake.Calls()).To(Equal([]auratest.Call{
+			{Method: "DestroyInstance", Args: []any{"abc123"}},
+			{Method: "DestroyInstance", Args: []any{"def456"}},
+			{Method: "UpdateInstance", Args: []any{"abc123", aura.UpdateRequest{Memory: "16GB"}}},
+		}))
+		Expect(fake.CallsTo("DestroyInstance")).To(HaveLen(2))
+		Expect(fake.CallsTo("DestroyInstance", "abc123")).To(HaveLen(1))
+		fake.Reset()
+		Expect(fake.Calls()).To(BeEmpty())
+	})
+	It("should assert calls", func() {
+		_ = fake.DestroyInstance("abc123")
+		fake.AssertCalled(t, "DestroyInstance")
+		fake.AssertCalledTimes(t, 1, "DestroyInstance", "abc123")
+		fake.AssertNotCalled(t, "DestroyInstance", "def456")
+		fake.AssertNotCalled(t, "PauseInstance")
+		Expect(t.errors).To(BeEmpty())
+
+		_ = fake.DestroyInstance("abc123")
+		fake.AssertCalledTimes(t, 1, "DestroyInstance", "abc123")
+		fake.AssertCalled(t, "PauseInstance", "abc123")
+		fake.AssertNotCalled(t, "DestroyInstance")
+		Expect(t.errors).To(HaveLen(3))
+		Expect(t.errors[0]).To(Equal("expected 1 calls to DestroyInstance[abc123], got 2 in calls " +
+			"[DestroyInstance[abc123] DestroyInstance[abc123]]"))
+	})
+})
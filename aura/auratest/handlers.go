@@ -0,0 +1,1023 @@
+package auratest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/indykite/aura-api-client/aura"
+)
+
+// instance is an instance in the store of the server. Once changed is more
+// than the transition delay ago the instance moves on to the next status, or
+// is removed if it was being destroyed.
+type instance struct {
+	data    aura.GetResponseData
+	pending bool
+	next    string
+	changed time.Time
+}
+
+type snapshot struct {
+	data    aura.Snapshot
+	changed time.Time
+}
+
+// customerManagedKey is a key in the store of the server, which becomes ready
+// or is removed once changed is more than the transition delay ago.
+type customerManagedKey struct {
+	data    aura.CustomerManagedKey
+	changed time.Time
+}
+
+// session is a Graph Analytics session in the store of the server, which
+// becomes ready once created is more than the transition delay ago, and is
+// removed once its expiry date has passed.
+type session struct {
+	data    aura.Session
+	created time.Time
+	expiry  time.Time
+}
+
+// AddInstance adds an instance to the store of the server, returning its ID.
+// An ID is generated when left out, and the status defaults to "running".
+func (s *Server) AddInstance(data aura.GetResponseData) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if data.ID == "" {
+		data.ID = s.newID()
+	}
+	if data.TenantID == "" {
+		data.TenantID = s.tenantID
+	}
+	if data.Status == "" {
+		data.Status = aura.StatusRunning
+	}
+	if data.MetricsIntegrationURL == "" {
+		data.MetricsIntegrationURL = s.metricsURL(data.TenantID, data.ID)
+	}
+	s.instances = append(s.instances, &instance{data: data, changed: time.Now()})
+	return data.ID
+}
+
+// Instance returns the instance with the given ID as currently stored by the server.
+func (s *Server) Instance(id string) (aura.GetResponseData, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := s.instance(id); i != nil {
+		return i.data, true
+	}
+	return aura.GetResponseData{}, false
+}
+
+// AddSnapshot adds a snapshot to the store of the server, returning its ID.
+// An ID is generated when left out, the status defaults to "Completed" and
+// the timestamp to the current time.
+func (s *Server) AddSnapshot(data aura.Snapshot) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if data.SnapshotID == "" {
+		data.SnapshotID = s.newID()
+	}
+	if data.Status == "" {
+		data.Status = aura.SnapshotStatusCompleted
+	}
+	if data.Profile == "" {
+		data.Profile = "AddHoc"
+	}
+	if data.Timestamp == "" {
+		data.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	}
+	s.snapshots = append(s.snapshots, &snapshot{data: data, changed: time.Now()})
+	return data.SnapshotID
+}
+
+// AddCustomerManagedKey adds a customer managed key to the store of the server,
+// returning its ID. An ID is generated when left out, and the status defaults
+// to "ready".
+func (s *Server) AddCustomerManagedKey(data aura.CustomerManagedKey) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if data.ID == "" {
+		data.ID = s.newID()
+	}
+	if data.TenantID == "" {
+		data.TenantID = s.tenantID
+	}
+	if data.Status == "" {
+		data.Status = aura.CustomerManagedKeyStatusReady
+	}
+	if data.Created == "" {
+		data.Created = time.Now().UTC().Format(time.RFC3339)
+	}
+	s.keys = append(s.keys, &customerManagedKey{data: data, changed: time.Now()})
+	return data.ID
+}
+
+// CustomerManagedKey returns the key with the given ID as currently stored by the server.
+func (s *Server) CustomerManagedKey(id string) (aura.CustomerManagedKey, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if k := s.key(id); k != nil {
+		return k.data, true
+	}
+	return aura.CustomerManagedKey{}, false
+}
+
+// AddSession adds a Graph Analytics session to the store of the server,
+// returning its ID. An ID is generated when left out, the status defaults to
+// "Ready" and the TTL to one hour.
+func (s *Server) AddSession(data aura.Session) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if data.ID == "" {
+		data.ID = s.newID()
+	}
+	if data.TenantID == "" {
+		data.TenantID = s.tenantID
+	}
+	if data.Status == "" {
+		data.Status = aura.SessionStatusReady
+	}
+	if data.TTL == "" {
+		data.TTL = defaultSessionTTL.String()
+	}
+	ttl, err := time.ParseDuration(data.TTL)
+	if err != nil {
+		panic(err)
+	}
+	sess := &session{created: time.Now(), expiry: time.Now().Add(ttl)}
+	sess.data = s.describeSession(data, sess)
+	s.sessions = append(s.sessions, sess)
+	return data.ID
+}
+
+// Session returns the session with the given ID as currently stored by the server.
+func (s *Server) Session(id string) (aura.Session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sess := s.session(id); sess != nil {
+		return sess.data, true
+	}
+	return aura.Session{}, false
+}
+
+func (s *Server) newID() string {
+	s.ids++
+	return fmt.Sprintf("%08x", 0xa0000000+s.ids)
+}
+
+// advance finishes the transitions which have taken long enough.
+func (s *Server) advance() {
+	instances := s.instances[:0]
+	for _, i := range s.instances {
+		if i.pending && time.Since(i.changed) >= s.delay {
+			i.data.Status = i.next
+			i.pending = false
+		}
+		if i.data.Status != "" {
+			instances = append(instances, i)
+		}
+	}
+	s.instances = instances
+	for _, snap := range s.snapshots {
+		if snap.data.Status == aura.SnapshotStatusInProgress && time.Since(snap.changed) >= s.delay {
+			snap.data.Status = aura.SnapshotStatusCompleted
+		}
+	}
+	keys := s.keys[:0]
+	for _, k := range s.keys {
+		if time.Since(k.changed) >= s.delay {
+			switch k.data.Status {
+			case aura.CustomerManagedKeyStatusPending:
+				k.data.Status = aura.CustomerManagedKeyStatusReady
+			case aura.CustomerManagedKeyStatusDeleting:
+				continue
+			}
+		}
+		keys = append(keys, k)
+	}
+	s.keys = keys
+	sessions := s.sessions[:0]
+	for _, sess := range s.sessions {
+		if time.Now().After(sess.expiry) {
+			continue
+		}
+		if sess.data.Status == aura.SessionStatusCreating && time.Since(sess.created) >= s.delay {
+			sess.data.Status = aura.SessionStatusReady
+		}
+		sessions = append(sessions, sess)
+	}
+	s.sessions = sessions
+}
+
+// transition moves the instance to a transitional status, after which it gets
+// the next status, or is removed if next is empty.
+func (s *Server) transition(i *instance, status, next string) {
+	i.data.Status = status
+	i.pending = true
+	i.next = next
+	i.changed = time.Now()
+}
+
+func (s *Server) instance(id string) *instance {
+	s.advance()
+	for _, i := range s.instances {
+		if i.data.ID == id {
+			return i
+		}
+	}
+	return nil
+}
+
+func (s *Server) tenant(id string) *aura.TenantResponseData {
+	for i := range s.tenants {
+		if s.tenants[i].ID == id {
+			return &s.tenants[i]
+		}
+	}
+	return nil
+}
+
+// token issues a token using the client credentials flow.
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "", "Method not allowed")
+		return
+	}
+	id, secret, ok := r.BasicAuth()
+	if !ok {
+		id, secret = r.PostFormValue("client_id"), r.PostFormValue("client_secret")
+	}
+	if id != s.clientID || secret != s.clientSecret || r.PostFormValue("grant_type") != "client_credentials" {
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "invalid_client"})
+		return
+	}
+	token := "auratest-token-" + s.newID()
+	s.tokens[token] = time.Now().Add(s.tokenExpiry)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": token,
+		"expires_in":   int(s.tokenExpiry.Seconds()),
+		"token_type":   "Bearer",
+	})
+}
+
+func (s *Server) authenticated(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	expiry, ok := s.tokens[token]
+	return ok && time.Now().Before(expiry)
+}
+
+// route sends the request to the handler of its method and path.
+func (s *Server) route(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) >= 4 && parts[0] == "api" && parts[1] == "v1" && parts[len(parts)-1] == "metrics" {
+		s.metricsIntegration(w, r, parts[2:len(parts)-1])
+		return
+	}
+	if len(parts) < 2 || parts[0] != "v1" {
+		writeError(w, http.StatusNotFound, "", "Not found")
+		return
+	}
+	parts = parts[1:]
+	switch {
+	case r.Method == http.MethodGet && len(parts) == 1 && parts[0] == "tenants":
+		s.listTenants(w)
+	case r.Method == http.MethodGet && len(parts) == 2 && parts[0] == "tenants":
+		s.getTenant(w, parts[1])
+	case r.Method == http.MethodGet && len(parts) == 3 && parts[0] == "tenants" && parts[2] == "metrics-integration":
+		s.getMetricsIntegration(w, parts[1])
+	case r.Method == http.MethodGet && len(parts) == 1 && parts[0] == "customer-managed-keys":
+		s.listCustomerManagedKeys(w, r)
+	case r.Method == http.MethodPost && len(parts) == 1 && parts[0] == "customer-managed-keys":
+		s.createCustomerManagedKey(w, r)
+	case r.Method == http.MethodGet && len(parts) == 2 && parts[0] == "customer-managed-keys":
+		s.getCustomerManagedKey(w, parts[1])
+	case r.Method == http.MethodDelete && len(parts) == 2 && parts[0] == "customer-managed-keys":
+		s.deleteCustomerManagedKey(w, parts[1])
+	case len(parts) >= 2 && parts[0] == "graph-analytics" && parts[1] == "sessions":
+		s.routeSessions(w, r, parts[2:])
+	case len(parts) == 0 || parts[0] != "instances":
+		writeError(w, http.StatusNotFound, "", "Not found")
+	case r.Method == http.MethodGet && len(parts) == 1:
+		s.listInstances(w, r)
+	case r.Method == http.MethodPost && len(parts) == 1:
+		s.createInstance(w, r)
+	case len(parts) == 2:
+		s.routeInstance(w, r, parts[1])
+	case r.Method == http.MethodPost && len(parts) == 3 && parts[2] == "pause":
+		s.pauseInstance(w, parts[1])
+	case r.Method == http.MethodPost && len(parts) == 3 && parts[2] == "resume":
+		s.resumeInstance(w, parts[1])
+	case r.Method == http.MethodPost && len(parts) == 3 && parts[2] == "overwrite":
+		s.overwriteInstance(w, r, parts[1])
+	case r.Method == http.MethodGet && len(parts) == 3 && parts[2] == "snapshots":
+		s.listSnapshots(w, r, parts[1])
+	case r.Method == http.MethodPost && len(parts) == 3 && parts[2] == "snapshots":
+		s.createSnapshot(w, parts[1])
+	case r.Method == http.MethodGet && len(parts) == 4 && parts[2] == "snapshots":
+		s.getSnapshot(w, parts[1], parts[3])
+	case r.Method == http.MethodPost && len(parts) == 5 && parts[2] == "snapshots" && parts[4] == "restore":
+		s.restoreSnapshot(w, parts[1], parts[3])
+	default:
+		writeError(w, http.StatusNotFound, "", "Not found")
+	}
+}
+
+func (s *Server) routeInstance(w http.ResponseWriter, r *http.Request, id string) {
+	switch r.Method {
+	case http.MethodGet:
+		s.getInstance(w, id)
+	case http.MethodPatch:
+		s.updateInstance(w, r, id)
+	case http.MethodDelete:
+		s.destroyInstance(w, id)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "", "Method not allowed")
+	}
+}
+
+func (s *Server) listTenants(w http.ResponseWriter) {
+	tenants := make([]aura.TenantSummary, 0, len(s.tenants))
+	for _, t := range s.tenants {
+		tenants = append(tenants, t.TenantSummary)
+	}
+	writeJSON(w, http.StatusOK, aura.ListTenantsResponse{Data: tenants})
+}
+
+func (s *Server) getTenant(w http.ResponseWriter, id string) {
+	t := s.tenant(id)
+	if t == nil {
+		writeError(w, http.StatusNotFound, "", "Tenant not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, aura.TenantResponse{Data: *t})
+}
+
+func (s *Server) getMetricsIntegration(w http.ResponseWriter, id string) {
+	if s.tenant(id) == nil {
+		writeError(w, http.StatusNotFound, "", "Tenant not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, aura.MetricsIntegrationResponse{
+		Data: aura.MetricsIntegration{Endpoint: s.metricsURL(id, "")},
+	})
+}
+
+func (s *Server) listInstances(w http.ResponseWriter, r *http.Request) {
+	s.advance()
+	tenantID := r.URL.Query().Get("tenantId")
+	instances := make([]aura.ListResponseData, 0, len(s.instances))
+	for _, i := range s.instances {
+		if tenantID == "" || i.data.TenantID == tenantID {
+			instances = append(instances, aura.ListResponseData{ResponseCommonProperties: i.data.ResponseCommonProperties})
+		}
+	}
+	writeJSON(w, http.StatusOK, aura.ListResponse{Data: instances})
+}
+
+func (s *Server) createInstance(w http.ResponseWriter, r *http.Request) {
+	var req aura.CreateInstanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "", "Invalid request body")
+		return
+	}
+	for field, value := range map[string]string{
+		"name":           req.Name,
+		"tenant_id":      req.TenantID,
+		"cloud_provider": string(req.CloudProvider),
+		"region":         req.Region,
+		"type":           string(req.InstanceType),
+		"version":        req.Version,
+		"memory":         req.Memory,
+	} {
+		if value == "" {
+			writeError(w, http.StatusBadRequest, field, "Missing required field")
+			return
+		}
+	}
+	if s.tenant(req.TenantID) == nil {
+		writeError(w, http.StatusForbidden, "tenant_id", "Access to the tenant is not allowed")
+		return
+	}
+	if req.CustomerManagedKeyID != "" {
+		if message := s.checkKey(req); message != "" {
+			writeError(w, http.StatusBadRequest, "customer_managed_key_id", message)
+			return
+		}
+	}
+	if req.Storage == "" {
+		req.Storage = storage(req.Memory)
+	}
+	id := s.newID()
+	i := &instance{data: aura.GetResponseData{
+		ResponseCommonProperties: aura.ResponseCommonProperties{
+			ID:            id,
+			Name:          req.Name,
+			TenantID:      req.TenantID,
+			ConnectionURL: "neo4j+s://" + id + ".databases.neo4j.io",
+			CloudProvider: string(req.CloudProvider),
+			Region:        req.Region,
+			InstanceType:  string(req.InstanceType),
+		},
+		Memory:                req.Memory,
+		Storage:               req.Storage,
+		CustomerManagedKeyID:  req.CustomerManagedKeyID,
+		MetricsIntegrationURL: s.metricsURL(req.TenantID, id),
+	}}
+	s.transition(i, aura.StatusCreating, aura.StatusRunning)
+	s.instances = append(s.instances, i)
+	writeJSON(w, http.StatusAccepted, aura.CreateResponse{Data: aura.CreateResponseData{
+		ResponseCommonProperties: i.data.ResponseCommonProperties,
+		Username:                 "neo4j",
+		Password:                 "auratest-password-" + id,
+	}})
+}
+
+func (s *Server) getInstance(w http.ResponseWriter, id string) {
+	i := s.instance(id)
+	if i == nil {
+		writeError(w, http.StatusNotFound, "", "Instance not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, aura.GetResponse{Data: i.data})
+}
+
+func (s *Server) updateInstance(w http.ResponseWriter, r *http.Request, id string) {
+	i := s.instance(id)
+	if i == nil {
+		writeError(w, http.StatusNotFound, "", "Instance not found")
+		return
+	}
+	var req struct {
+		Name   string `json:"name"`
+		Memory string `json:"memory"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "", "Invalid request body")
+		return
+	}
+	if req.Memory != "" && i.data.Status != aura.StatusRunning {
+		writeError(w, http.StatusConflict, "", "The instance must be running to be resized")
+		return
+	}
+	if req.Name != "" {
+		i.data.Name = req.Name
+	}
+	if req.Memory != "" && req.Memory != i.data.Memory {
+		i.data.Memory = req.Memory
+		i.data.Storage = storage(req.Memory)
+		s.transition(i, aura.StatusUpdating, aura.StatusRunning)
+	}
+	writeJSON(w, http.StatusOK, aura.GetResponse{Data: i.data})
+}
+
+func (s *Server) destroyInstance(w http.ResponseWriter, id string) {
+	i := s.instance(id)
+	if i == nil {
+		writeError(w, http.StatusNotFound, "", "Instance not found")
+		return
+	}
+	if i.data.Status != aura.StatusDestroying {
+		s.transition(i, aura.StatusDestroying, "")
+	}
+	writeJSON(w, http.StatusAccepted, aura.GetResponse{Data: i.data})
+}
+
+func (s *Server) pauseInstance(w http.ResponseWriter, id string) {
+	s.changeStatus(w, id, aura.StatusRunning, aura.StatusPausing, aura.StatusPaused)
+}
+
+func (s *Server) resumeInstance(w http.ResponseWriter, id string) {
+	s.changeStatus(w, id, aura.StatusPaused, aura.StatusResuming, aura.StatusRunning)
+}
+
+// changeStatus moves an instance with status from through status to next,
+// rejecting the request if the instance has another status.
+func (s *Server) changeStatus(w http.ResponseWriter, id, from, status, next string) {
+	i := s.instance(id)
+	if i == nil {
+		writeError(w, http.StatusNotFound, "", "Instance not found")
+		return
+	}
+	if i.data.Status != from {
+		writeError(w, http.StatusBadRequest, "", fmt.Sprintf("The instance is %s, not %s", i.data.Status, from))
+		return
+	}
+	s.transition(i, status, next)
+	writeJSON(w, http.StatusAccepted, aura.GetResponse{Data: i.data})
+}
+
+func (s *Server) overwriteInstance(w http.ResponseWriter, r *http.Request, id string) {
+	i := s.instance(id)
+	if i == nil {
+		writeError(w, http.StatusNotFound, "", "Instance not found")
+		return
+	}
+	var req struct {
+		SourceInstanceID string `json:"source_instance_id"`
+		SourceSnapshotID string `json:"source_snapshot_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "", "Invalid request body")
+		return
+	}
+	switch {
+	case req.SourceInstanceID != "":
+		if s.instance(req.SourceInstanceID) == nil {
+			writeError(w, http.StatusNotFound, "source_instance_id", "Source instance not found")
+			return
+		}
+	case req.SourceSnapshotID != "":
+		if s.snapshot(req.SourceSnapshotID) == nil {
+			writeError(w, http.StatusNotFound, "source_snapshot_id", "Source snapshot not found")
+			return
+		}
+	default:
+		writeError(w, http.StatusBadRequest, "source_instance_id", "Missing source")
+		return
+	}
+	if i.data.Status != aura.StatusRunning {
+		writeError(w, http.StatusConflict, "", "The instance must be running to be overwritten")
+		return
+	}
+	s.transition(i, aura.StatusOverwriting, aura.StatusRunning)
+	writeJSON(w, http.StatusAccepted, aura.GetResponse{Data: i.data})
+}
+
+func (s *Server) snapshot(id string) *snapshot {
+	s.advance()
+	for _, snap := range s.snapshots {
+		if snap.data.SnapshotID == id {
+			return snap
+		}
+	}
+	return nil
+}
+
+func (s *Server) listSnapshots(w http.ResponseWriter, r *http.Request, instanceID string) {
+	if s.instance(instanceID) == nil {
+		writeError(w, http.StatusNotFound, "", "Instance not found")
+		return
+	}
+	// Aura defaults to the snapshots taken today
+	date := r.URL.Query().Get("date")
+	if date == "" {
+		date = time.Now().UTC().Format(time.DateOnly)
+	}
+	snapshots := []aura.Snapshot{}
+	for _, snap := range s.snapshots {
+		if snap.data.InstanceID == instanceID && strings.HasPrefix(snap.data.Timestamp, date) {
+			snapshots = append(snapshots, snap.data)
+		}
+	}
+	writeJSON(w, http.StatusOK, aura.ListSnapshotsResponse{Data: snapshots})
+}
+
+func (s *Server) createSnapshot(w http.ResponseWriter, instanceID string) {
+	i := s.instance(instanceID)
+	if i == nil {
+		writeError(w, http.StatusNotFound, "", "Instance not found")
+		return
+	}
+	if i.data.Status != aura.StatusRunning {
+		writeError(w, http.StatusConflict, "", "The instance must be running to take a snapshot")
+		return
+	}
+	snap := &snapshot{
+		data: aura.Snapshot{
+			InstanceID: instanceID,
+			SnapshotID: s.newID(),
+			Profile:    "AddHoc",
+			Status:     aura.SnapshotStatusInProgress,
+			Timestamp:  time.Now().UTC().Format(time.RFC3339),
+		},
+		changed: time.Now(),
+	}
+	s.snapshots = append(s.snapshots, snap)
+	writeJSON(w, http.StatusAccepted, aura.CreateSnapshotResponse{
+		Data: aura.CreateSnapshotResponseData{SnapshotID: snap.data.SnapshotID},
+	})
+}
+
+func (s *Server) getSnapshot(w http.ResponseWriter, instanceID, snapshotID string) {
+	snap := s.snapshot(snapshotID)
+	if snap == nil || snap.data.InstanceID != instanceID {
+		writeError(w, http.StatusNotFound, "", "Snapshot not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, aura.GetSnapshotResponse{Data: snap.data})
+}
+
+func (s *Server) restoreSnapshot(w http.ResponseWriter, instanceID, snapshotID string) {
+	i := s.instance(instanceID)
+	snap := s.snapshot(snapshotID)
+	if i == nil || snap == nil || snap.data.InstanceID != instanceID {
+		writeError(w, http.StatusNotFound, "", "Snapshot not found")
+		return
+	}
+	if snap.data.Status != aura.SnapshotStatusCompleted || i.data.Status != aura.StatusRunning {
+		writeError(w, http.StatusConflict, "", "The snapshot cannot be restored right now")
+		return
+	}
+	s.transition(i, aura.StatusRestoring, aura.StatusRunning)
+	writeJSON(w, http.StatusAccepted, aura.GetResponse{Data: i.data})
+}
+
+func (s *Server) key(id string) *customerManagedKey {
+	s.advance()
+	for _, k := range s.keys {
+		if k.data.ID == id {
+			return k
+		}
+	}
+	return nil
+}
+
+// checkKey returns why the key of the request cannot encrypt the instance,
+// or an empty string if it can.
+func (s *Server) checkKey(req aura.CreateInstanceRequest) string {
+	k := s.key(req.CustomerManagedKeyID)
+	switch {
+	case k == nil || k.data.TenantID != req.TenantID:
+		return "Customer managed key not found"
+	case k.data.Status != aura.CustomerManagedKeyStatusReady:
+		return fmt.Sprintf("The customer managed key is %s, not ready", k.data.Status)
+	case k.data.CloudProvider != req.CloudProvider || k.data.Region != req.Region ||
+		k.data.InstanceType != req.InstanceType:
+		return "The customer managed key cannot encrypt instances of this cloud provider, region and type"
+	}
+	return ""
+}
+
+func (s *Server) listCustomerManagedKeys(w http.ResponseWriter, r *http.Request) {
+	s.advance()
+	tenantID := r.URL.Query().Get("tenantId")
+	keys := make([]aura.CustomerManagedKeySummary, 0, len(s.keys))
+	for _, k := range s.keys {
+		if tenantID == "" || k.data.TenantID == tenantID {
+			keys = append(keys, k.data.CustomerManagedKeySummary)
+		}
+	}
+	writeJSON(w, http.StatusOK, aura.ListCustomerManagedKeysResponse{Data: keys})
+}
+
+func (s *Server) createCustomerManagedKey(w http.ResponseWriter, r *http.Request) {
+	var req aura.CreateCustomerManagedKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "", "Invalid request body")
+		return
+	}
+	for _, f := range [][2]string{
+		{"name", req.Name},
+		{"tenant_id", req.TenantID},
+		{"key_id", req.KeyID},
+		{"cloud_provider", string(req.CloudProvider)},
+		{"region", req.Region},
+		{"type", string(req.InstanceType)},
+	} {
+		if f[1] == "" {
+			writeError(w, http.StatusBadRequest, f[0], "Missing required field")
+			return
+		}
+	}
+	if s.tenant(req.TenantID) == nil {
+		writeError(w, http.StatusForbidden, "tenant_id", "Access to the tenant is not allowed")
+		return
+	}
+	k := &customerManagedKey{
+		data: aura.CustomerManagedKey{
+			CustomerManagedKeySummary: aura.CustomerManagedKeySummary{
+				ID:       s.newID(),
+				Name:     req.Name,
+				TenantID: req.TenantID,
+			},
+			Status:        aura.CustomerManagedKeyStatusPending,
+			Created:       time.Now().UTC().Format(time.RFC3339),
+			CloudProvider: req.CloudProvider,
+			KeyID:         req.KeyID,
+			Region:        req.Region,
+			InstanceType:  req.InstanceType,
+		},
+		changed: time.Now(),
+	}
+	s.keys = append(s.keys, k)
+	writeJSON(w, http.StatusAccepted, aura.CustomerManagedKeyResponse{Data: k.data})
+}
+
+func (s *Server) getCustomerManagedKey(w http.ResponseWriter, id string) {
+	k := s.key(id)
+	if k == nil {
+		writeError(w, http.StatusNotFound, "", "Customer managed key not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, aura.CustomerManagedKeyResponse{Data: k.data})
+}
+
+// deleteCustomerManagedKey refuses to delete keys encrypting instances, like Aura.
+func (s *Server) deleteCustomerManagedKey(w http.ResponseWriter, id string) {
+	k := s.key(id)
+	if k == nil {
+		writeError(w, http.StatusNotFound, "", "Customer managed key not found")
+		return
+	}
+	for _, i := range s.instances {
+		if i.data.CustomerManagedKeyID == id && i.data.Status != aura.StatusDestroying {
+			writeError(w, http.StatusConflict, "", "The customer managed key is used by instance "+i.data.ID)
+			return
+		}
+	}
+	if k.data.Status != aura.CustomerManagedKeyStatusDeleting {
+		k.data.Status = aura.CustomerManagedKeyStatusDeleting
+		k.changed = time.Now()
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+const defaultSessionTTL = time.Hour
+
+// sessionSizes are the amounts of memory sessions can have, in GB.
+var sessionSizes = []int{2, 4, 8, 16, 32, 64, 128, 256, 512}
+
+func (s *Server) routeSessions(w http.ResponseWriter, r *http.Request, parts []string) {
+	switch {
+	case r.Method == http.MethodGet && len(parts) == 0:
+		s.listSessions(w, r)
+	case r.Method == http.MethodPost && len(parts) == 0:
+		s.createSession(w, r)
+	case r.Method == http.MethodPost && len(parts) == 1 && parts[0] == "sizing":
+		s.estimateSessionSize(w, r)
+	case r.Method == http.MethodGet && len(parts) == 1:
+		s.getSession(w, parts[0])
+	case r.Method == http.MethodDelete && len(parts) == 1:
+		s.deleteSession(w, parts[0])
+	default:
+		writeError(w, http.StatusNotFound, "", "Not found")
+	}
+}
+
+func (s *Server) session(id string) *session {
+	s.advance()
+	for _, sess := range s.sessions {
+		if sess.data.ID == id {
+			return sess
+		}
+	}
+	return nil
+}
+
+// describeSession fills in the fields of a session derived from its ID and times.
+func (s *Server) describeSession(data aura.Session, sess *session) aura.Session {
+	data.Host = data.ID + ".sessions.neo4j.io"
+	data.CreatedAt = sess.created.UTC().Format(time.RFC3339)
+	data.ExpiryDate = sess.expiry.UTC().Format(time.RFC3339)
+	return data
+}
+
+// estimateSessionSize gives a rough estimate, growing with the size of the
+// graph and the number of algorithm categories, so tests can tell graphs apart.
+func (s *Server) estimateSessionSize(w http.ResponseWriter, r *http.Request) {
+	var req aura.SessionSizeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "", "Invalid request body")
+		return
+	}
+	if req.NodeCount <= 0 {
+		writeError(w, http.StatusBadRequest, "node_count", "The node count must be positive")
+		return
+	}
+	if req.RelationshipCount < 0 {
+		writeError(w, http.StatusBadRequest, "relationship_count", "The relationship count must not be negative")
+		return
+	}
+	bytes := float64(req.NodeCount*64+req.RelationshipCount*24) * (1 + 0.5*float64(len(req.AlgorithmCategories)))
+	estimate := max(1, int(math.Ceil(bytes/1e9)))
+	size := aura.SessionSize{EstimatedMemory: strconv.Itoa(estimate) + "GB", DidExceedMaximum: true}
+	size.RecommendedSize = strconv.Itoa(sessionSizes[len(sessionSizes)-1]) + "GB"
+	for _, gb := range sessionSizes {
+		if gb >= estimate {
+			size.RecommendedSize = strconv.Itoa(gb) + "GB"
+			size.DidExceedMaximum = false
+			break
+		}
+	}
+	writeJSON(w, http.StatusOK, aura.SessionSizeResponse{Data: size})
+}
+
+func (s *Server) listSessions(w http.ResponseWriter, r *http.Request) {
+	s.advance()
+	tenantID := r.URL.Query().Get("tenantId")
+	instanceID := r.URL.Query().Get("instanceId")
+	sessions := make([]aura.SessionSummary, 0, len(s.sessions))
+	for _, sess := range s.sessions {
+		if (tenantID == "" || sess.data.TenantID == tenantID) &&
+			(instanceID == "" || sess.data.InstanceID == instanceID) {
+			sessions = append(sessions, sess.data.SessionSummary)
+		}
+	}
+	writeJSON(w, http.StatusOK, aura.ListSessionsResponse{Data: sessions})
+}
+
+func (s *Server) createSession(w http.ResponseWriter, r *http.Request) {
+	var req aura.CreateSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "", "Invalid request body")
+		return
+	}
+	for _, f := range [][2]string{
+		{"name", req.Name},
+		{"tenant_id", req.TenantID},
+		{"instance_id", req.InstanceID},
+		{"memory", req.Memory},
+	} {
+		if f[1] == "" {
+			writeError(w, http.StatusBadRequest, f[0], "Missing required field")
+			return
+		}
+	}
+	if s.tenant(req.TenantID) == nil {
+		writeError(w, http.StatusForbidden, "tenant_id", "Access to the tenant is not allowed")
+		return
+	}
+	gb, err := strconv.Atoi(strings.TrimSuffix(req.Memory, "GB"))
+	if err != nil || !slices.Contains(sessionSizes, gb) {
+		writeError(w, http.StatusBadRequest, "memory", "Unsupported session memory "+req.Memory)
+		return
+	}
+	if req.TTL == "" {
+		req.TTL = defaultSessionTTL.String()
+	}
+	ttl, err := time.ParseDuration(req.TTL)
+	if err != nil || ttl <= 0 || ttl > 7*24*time.Hour {
+		writeError(w, http.StatusBadRequest, "ttl", "The TTL must be a duration of at most 7 days")
+		return
+	}
+	i := s.instance(req.InstanceID)
+	if i == nil || i.data.TenantID != req.TenantID {
+		writeError(w, http.StatusNotFound, "instance_id", "Instance not found")
+		return
+	}
+	if i.data.Status != aura.StatusRunning {
+		writeError(w, http.StatusConflict, "instance_id", "The instance must be running to attach a session")
+		return
+	}
+	sess := &session{created: time.Now(), expiry: time.Now().Add(ttl)}
+	sess.data = s.describeSession(aura.Session{
+		SessionSummary: aura.SessionSummary{
+			ID:         s.newID(),
+			Name:       req.Name,
+			TenantID:   req.TenantID,
+			InstanceID: req.InstanceID,
+		},
+		Status: aura.SessionStatusCreating,
+		Memory: req.Memory,
+		TTL:    req.TTL,
+	}, sess)
+	s.sessions = append(s.sessions, sess)
+	writeJSON(w, http.StatusAccepted, aura.SessionResponse{Data: sess.data})
+}
+
+func (s *Server) getSession(w http.ResponseWriter, id string) {
+	sess := s.session(id)
+	if sess == nil {
+		writeError(w, http.StatusNotFound, "", "Session not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, aura.SessionResponse{Data: sess.data})
+}
+
+func (s *Server) deleteSession(w http.ResponseWriter, id string) {
+	if s.session(id) == nil {
+		writeError(w, http.StatusNotFound, "", "Session not found")
+		return
+	}
+	s.sessions = slices.DeleteFunc(s.sessions, func(sess *session) bool { return sess.data.ID == id })
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetMetric sets the value the metrics integration reports for the metric of
+// an instance, i.e. aura.AuraMetricCPUUsage. Metrics of running instances
+// which have not been set are 0, except the limits which follow from the
+// memory and storage of the instance.
+func (s *Server) SetMetric(instanceID, name string, value float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.metrics[instanceID] == nil {
+		s.metrics[instanceID] = make(map[string]float64)
+	}
+	s.metrics[instanceID][name] = value
+}
+
+// metricsURL returns the metrics integration endpoint of the tenant, or of
+// the instance if an ID is given.
+func (s *Server) metricsURL(tenantID, instanceID string) string {
+	if s.Server == nil {
+		return ""
+	}
+	if instanceID == "" {
+		return s.URL + "/api/v1/" + tenantID + "/metrics"
+	}
+	return s.URL + "/api/v1/" + tenantID + "/" + instanceID + "/metrics"
+}
+
+// metricsIntegration serves the metrics of the running instances of a tenant,
+// or of a single instance, in the Prometheus text format like Aura.
+func (s *Server) metricsIntegration(w http.ResponseWriter, r *http.Request, parts []string) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "", "Method not allowed")
+		return
+	}
+	if s.tenant(parts[0]) == nil || len(parts) > 2 {
+		writeError(w, http.StatusNotFound, "", "Not found")
+		return
+	}
+	s.advance()
+	metrics := []struct {
+		name, help  string
+		perDatabase bool
+	}{
+		{aura.AuraMetricCPUUsage, "CPU cores used.", false},
+		{aura.AuraMetricCPULimit, "CPU cores available.", false},
+		{aura.AuraMetricHeapUsedRatio, "Fraction of the heap in use.", false},
+		{aura.AuraMetricStorageLimit, "Bytes of storage available.", false},
+		{aura.AuraMetricStoreSize, "Bytes used by the store of the database.", true},
+		{aura.AuraMetricQueriesSucceeded, "Queries which succeeded.", true},
+		{aura.AuraMetricQueriesFailed, "Queries which failed.", true},
+	}
+	now := time.Now().UnixMilli()
+	var b strings.Builder
+	for _, m := range metrics {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", m.name, m.help, m.name)
+		for _, i := range s.instances {
+			if i.data.TenantID != parts[0] || (len(parts) == 2 && i.data.ID != parts[1]) ||
+				i.data.Status != aura.StatusRunning {
+				continue
+			}
+			value, ok := s.metrics[i.data.ID][m.name]
+			if !ok {
+				value = defaultMetric(m.name, i.data)
+			}
+			labels := fmt.Sprintf(`instance_id=%q`, i.data.ID)
+			if m.perDatabase {
+				labels += `,database="neo4j"`
+			}
+			for _, aggregation := range []string{"MIN", "MAX", "AVG"} {
+				fmt.Fprintf(&b, "%s{%s,aggregation=%q} %g %d\n", m.name, labels, aggregation, value, now)
+			}
+		}
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	_, _ = io.WriteString(w, b.String())
+}
+
+// defaultMetric returns the value of metrics which have not been set, with
+// a CPU core for each 4GB of memory like Aura.
+func defaultMetric(name string, data aura.GetResponseData) float64 {
+	gb := func(amount string) float64 {
+		n, _ := strconv.Atoi(strings.TrimSuffix(amount, "GB"))
+		return float64(n)
+	}
+	switch name {
+	case aura.AuraMetricCPULimit:
+		return max(1, gb(data.Memory)/4)
+	case aura.AuraMetricStorageLimit:
+		return gb(data.Storage) * (1 << 30)
+	}
+	return 0
+}
+
+// storage returns the storage Aura allocates for the given amount of memory,
+// which is twice the memory.
+func storage(memory string) string {
+	gb, err := strconv.Atoi(strings.TrimSuffix(memory, "GB"))
+	if err != nil {
+		return ""
+	}
+	return strconv.Itoa(2*gb) + "GB"
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_, _ = w.Write(b)
+}
+
+// writeError responds with an error in the format used by Aura.
+func writeError(w http.ResponseWriter, code int, field, message string) {
+	writeJSON(w, code, map[string]any{
+		"errors": []aura.ErrorDetail{{Message: message, Reason: http.StatusText(code), Field: field}},
+	})
+}
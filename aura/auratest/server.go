@@ -0,0 +1,237 @@
+// Package auratest provides a fake Neo4J Aura API for testing code using the
+// aura package without access to Aura. The fake keeps its instances, snapshots,
+// customer managed keys, Graph Analytics sessions and tenants in memory, and
+// can be made to fail or slow down requests.
+package auratest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/indykite/aura-api-client/aura"
+)
+
+// Credentials and tenant of a Server unless changed using WithCredentials and WithTenant.
+const (
+	DefaultClientID     = "auratest-client"
+	DefaultClientSecret = "auratest-secret"
+	DefaultTenantID     = "auratest-tenant"
+)
+
+const defaultTokenExpiry = time.Hour
+
+// Server is a fake Aura API served over HTTP. Instances move through the same
+// statuses as in Aura, i.e. "creating" before "running", staying in each
+// transitional status for the transition delay of the server.
+type Server struct {
+	*httptest.Server
+
+	mu           sync.Mutex
+	clientID     string
+	clientSecret string
+	tenantID     string
+	tenants      []aura.TenantResponseData
+	delay        time.Duration
+	tokenExpiry  time.Duration
+	tokens       map[string]time.Time
+	instances    []*instance
+	snapshots    []*snapshot
+	keys         []*customerManagedKey
+	sessions     []*session
+	metrics      map[string]map[string]float64
+	faults       []*Fault
+	requests     []Request
+	ids          int
+}
+
+// Option customizes a Server.
+type Option func(*Server)
+
+// WithCredentials sets the client ID and secret accepted by the token endpoint.
+func WithCredentials(clientID, clientSecret string) Option {
+	return func(s *Server) {
+		s.clientID = clientID
+		s.clientSecret = clientSecret
+	}
+}
+
+// WithTenant adds a tenant to the server. The first tenant added replaces the
+// default tenant, and is the tenant used by Client.
+func WithTenant(tenant aura.TenantResponseData) Option {
+	return func(s *Server) {
+		if s.tenants[0].ID == DefaultTenantID {
+			s.tenants = nil
+			s.tenantID = tenant.ID
+		}
+		s.tenants = append(s.tenants, tenant)
+	}
+}
+
+// WithTransitionDelay sets how long instances, snapshots, keys and sessions
+// stay in transitional statuses such as "creating" or "pausing". By default
+// transitions are finished by the time of the next request.
+func WithTransitionDelay(d time.Duration) Option {
+	return func(s *Server) {
+		s.delay = d
+	}
+}
+
+// WithTokenExpiry sets how long tokens issued by the server are valid, one hour by default.
+func WithTokenExpiry(d time.Duration) Option {
+	return func(s *Server) {
+		s.tokenExpiry = d
+	}
+}
+
+// NewServer starts a fake Aura API, which should be closed when done.
+func NewServer(options ...Option) *Server {
+	s := &Server{
+		clientID:     DefaultClientID,
+		clientSecret: DefaultClientSecret,
+		tenantID:     DefaultTenantID,
+		tenants:      []aura.TenantResponseData{defaultTenant()},
+		tokenExpiry:  defaultTokenExpiry,
+		tokens:       make(map[string]time.Time),
+		metrics:      make(map[string]map[string]float64),
+	}
+	for _, o := range options {
+		o(s)
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Client returns a client using the server, authenticated as its tenant.
+func (s *Server) Client(ctx context.Context, options ...aura.Option) (aura.Client, error) {
+	options = append([]aura.Option{aura.WithEndpoint(s.URL)}, options...)
+	return aura.NewClient(ctx, s.clientID, s.clientSecret, s.tenantID, options...)
+}
+
+func defaultTenant() aura.TenantResponseData {
+	var configurations []aura.InstanceConfiguration
+	for _, t := range []aura.InstanceType{aura.InstanceTypeProfessionalDB, aura.InstanceTypeEnterpriseDB} {
+		for _, memory := range []string{"2GB", "4GB", "8GB", "16GB"} {
+			configurations = append(configurations, aura.InstanceConfiguration{
+				CloudProvider: string(aura.CloudProviderGCP),
+				Region:        "europe-west1",
+				RegionName:    "Belgium (europe-west1)",
+				Memory:        memory,
+				Storage:       storage(memory),
+				InstanceType:  string(t),
+				Version:       "5",
+			})
+		}
+	}
+	return aura.TenantResponseData{
+		TenantSummary:          aura.TenantSummary{ID: DefaultTenantID, Name: "auratest"},
+		InstanceConfigurations: configurations,
+	}
+}
+
+// Fault describes how requests to the server fail. Requests matching the
+// method and path are delayed by the latency, and then answered with the
+// status unless it is 0.
+type Fault struct {
+	Method     string        // Method of the failing requests, all methods if empty
+	Path       string        // Pattern as used by path.Match, i.e. "/v1/instances/*", all paths if empty
+	Status     int           // Status to respond with, i.e. 503 or 429
+	RetryAfter time.Duration // Value of the Retry-After header, which is left out if 0
+	Latency    time.Duration // Time to wait before responding
+	Times      int           // Number of requests to fail, all requests if 0
+}
+
+// InjectFault makes requests fail as described by f. When several faults match
+// a request the one injected first is used.
+func (s *Server) InjectFault(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// ClearFaults removes all injected faults.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// Request is a request received by the server.
+type Request struct {
+	Method string
+	Path   string
+}
+
+// Requests returns the requests received so far, including failed ones.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// fault returns the fault to apply to the request, if any.
+func (s *Server) fault(r *http.Request) *Fault {
+	for i, f := range s.faults {
+		if f.Method != "" && f.Method != r.Method {
+			continue
+		}
+		if f.Path != "" {
+			if ok, _ := path.Match(f.Path, r.URL.Path); !ok {
+				continue
+			}
+		}
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				s.faults = append(s.faults[:i:i], s.faults[i+1:]...)
+			}
+		}
+		return f
+	}
+	return nil
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{Method: r.Method, Path: r.URL.Path})
+	w.Header().Set("X-Request-Id", fmt.Sprintf("auratest-%d", len(s.requests)))
+	var f Fault
+	if fault := s.fault(r); fault != nil {
+		f = *fault
+	}
+	s.mu.Unlock()
+
+	if f.Latency > 0 {
+		timer := time.NewTimer(f.Latency)
+		select {
+		case <-r.Context().Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+	if f.Status != 0 {
+		if f.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int((f.RetryAfter+time.Second-1)/time.Second)))
+		}
+		writeError(w, f.Status, "", "Injected fault")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.URL.Path == "/oauth/token" {
+		s.token(w, r)
+		return
+	}
+	if !s.authenticated(r) {
+		writeError(w, http.StatusUnauthorized, "", "Invalid or expired token")
+		return
+	}
+	s.route(w, r)
+}
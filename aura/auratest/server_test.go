@@ -0,0 +1,329 @@
+package auratest_test
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+
+	"github.com/indykite/aura-api-client/aura"
+	"github.com/indykite/aura-api-client/aura/auratest"
+)
+
+var _ = Describe("Server", func() {
+	var (
+		server *auratest.Server
+		client aura.Client
+		ctx    context.Context
+		fast   = aura.WithPollInterval(5*time.Millisecond, 10*time.Millisecond)
+	)
+	BeforeEach(func() {
+		ctx = context.Background()
+		server = auratest.NewServer(auratest.WithTransitionDelay(20 * time.Millisecond))
+		DeferCleanup(server.Close)
+		var err error
+		client, err = server.Client(ctx)
+		Expect(err).To(Succeed())
+	})
+	create := func() string {
+		resp, err := client.CreateInstance("Production", "gcp", "8GB", "5", "europe-west1", "enterprise-db")
+		Expect(err).To(Succeed())
+		Expect(resp.Data.Password).NotTo(BeEmpty())
+		return resp.Data.ID
+	}
+	Describe("Instances", func() {
+		It("should be created", func() {
+			id := create()
+			actual, err := client.GetInstance(id)
+			Expect(err).To(Succeed())
+			Expect(actual.Data.Status).To(Equal(aura.StatusCreating))
+			Expect(actual.Data.TenantID).To(Equal(auratest.DefaultTenantID))
+			Expect(actual.Data.Storage).To(Equal("16GB"))
+
+			actual, err = client.WaitForStatus(ctx, id, []string{aura.StatusRunning}, fast)
+			Expect(err).To(Succeed())
+			Expect(actual.Data.Name).To(Equal("Production"))
+
+			list, err := client.ListInstances(auratest.DefaultTenantID)
+			Expect(err).To(Succeed())
+			Expect(list.Data).To(HaveLen(1))
+		})
+		It("should reject incomplete requests", func() {
+			_, err := client.CreateInstanceFromRequest(aura.CreateInstanceRequest{Name: "Production"})
+			Expect(err).To(MatchError(aura.ErrValidation))
+		})
+		It("should be paused and resumed", func() {
+			id := server.AddInstance(aura.GetResponseData{Memory: "8GB"})
+			Expect(client.PauseInstance(id)).To(Succeed())
+			Expect(client.WaitForStatus(ctx, id, []string{aura.StatusPaused}, fast)).NotTo(BeNil())
+			Expect(client.PauseInstance(id)).To(MatchError(aura.ErrValidation))
+
+			Expect(client.ResumeInstance(id)).To(Succeed())
+			actual, _ := server.Instance(id)
+			Expect(actual.Status).To(Equal(aura.StatusResuming))
+			Expect(client.WaitForStatus(ctx, id, []string{aura.StatusRunning}, fast)).NotTo(BeNil())
+		})
+		It("should be resized", func() {
+			id := server.AddInstance(aura.GetResponseData{Memory: "8GB"})
+			actual, err := client.UpdateInstance(id, aura.UpdateRequest{Memory: "16GB"})
+			Expect(err).To(Succeed())
+			Expect(actual.Data.Status).To(Equal(aura.StatusUpdating))
+			Expect(actual.Data.Memory).To(Equal("16GB"))
+		})
+		It("should be removed once destroyed", func() {
+			id := server.AddInstance(aura.GetResponseData{})
+			Expect(client.DestroyInstance(id)).To(Succeed())
+			actual, err := client.GetInstance(id)
+			Expect(err).To(Succeed())
+			Expect(actual.Data.Status).To(Equal(aura.StatusDestroying))
+			Eventually(func() error {
+				_, err := client.GetInstance(id)
+				return err
+			}).Should(MatchError(aura.ErrNotFound))
+		})
+	})
+	Describe("Snapshots", func() {
+		It("should be taken and restored", func() {
+			id := server.AddInstance(aura.GetResponseData{})
+			created, err := client.CreateSnapshot(id)
+			Expect(err).To(Succeed())
+			snapshotID := created.Data.SnapshotID
+			Eventually(func() string {
+				snap, err := client.GetSnapshot(id, snapshotID)
+				Expect(err).To(Succeed())
+				return snap.Data.Status
+			}).Should(Equal(aura.SnapshotStatusCompleted))
+
+			list, err := client.ListSnapshots(id, "")
+			Expect(err).To(Succeed())
+			Expect(list.Data).To(HaveLen(1))
+			list, err = client.ListSnapshots(id, "2020-01-01")
+			Expect(err).To(Succeed())
+			Expect(list.Data).To(BeEmpty())
+
+			actual, err := client.RestoreSnapshot(id, snapshotID)
+			Expect(err).To(Succeed())
+			Expect(actual.Data.Status).To(Equal(aura.StatusRestoring))
+		})
+	})
+	Describe("Customer managed keys", func() {
+		request := aura.CreateInstanceRequest{
+			Name:          "Encrypted",
+			CloudProvider: aura.CloudProviderGCP,
+			Region:        "europe-west1",
+			InstanceType:  aura.InstanceTypeEnterpriseDB,
+			Memory:        "8GB",
+			Version:       "5",
+		}
+		It("should become ready and encrypt instances", func() {
+			created, err := client.CreateCustomerManagedKey(aura.CreateCustomerManagedKeyRequest{
+				Name:          "Production key",
+				KeyID:         "projects/p/locations/europe-west1/keyRings/r/cryptoKeys/k",
+				CloudProvider: aura.CloudProviderGCP,
+				Region:        "europe-west1",
+				InstanceType:  aura.InstanceTypeEnterpriseDB,
+			})
+			Expect(err).To(Succeed())
+			Expect(created.Data.Status).To(Equal(aura.CustomerManagedKeyStatusPending))
+			request.CustomerManagedKeyID = created.Data.ID
+			_, err = client.CreateInstanceFromRequest(request)
+			Expect(err).To(MatchError(aura.ErrValidation))
+
+			key, err := client.WaitForCustomerManagedKey(ctx, created.Data.ID, fast)
+			Expect(err).To(Succeed())
+			Expect(key.Data.Status).To(Equal(aura.CustomerManagedKeyStatusReady))
+			instance, err := client.CreateInstanceFromRequest(request)
+			Expect(err).To(Succeed())
+			actual, _ := server.Instance(instance.Data.ID)
+			Expect(actual.CustomerManagedKeyID).To(Equal(created.Data.ID))
+
+			list, err := client.ListCustomerManagedKeys(auratest.DefaultTenantID)
+			Expect(err).To(Succeed())
+			Expect(list.Data).To(ConsistOf(HaveField("Name", "Production key")))
+		})
+		It("should only be deleted when not in use", func() {
+			id := server.AddCustomerManagedKey(aura.CustomerManagedKey{
+				CloudProvider: aura.CloudProviderGCP,
+				Region:        "europe-west1",
+				InstanceType:  aura.InstanceTypeEnterpriseDB,
+			})
+			request.CustomerManagedKeyID = id
+			instance, err := client.CreateInstanceFromRequest(request)
+			Expect(err).To(Succeed())
+			Expect(client.DeleteCustomerManagedKey(id)).To(MatchError(aura.ErrConflict))
+
+			Expect(client.DestroyInstance(instance.Data.ID)).To(Succeed())
+			Expect(client.DeleteCustomerManagedKey(id)).To(Succeed())
+			key, err := client.GetCustomerManagedKey(id)
+			Expect(err).To(Succeed())
+			Expect(key.Data.Status).To(Equal(aura.CustomerManagedKeyStatusDeleting))
+			Eventually(func() bool {
+				_, ok := server.CustomerManagedKey(id)
+				return ok
+			}).Should(BeFalse())
+		})
+		It("should not encrypt instances of other regions", func() {
+			id := server.AddCustomerManagedKey(aura.CustomerManagedKey{
+				CloudProvider: aura.CloudProviderAWS,
+				Region:        "us-east-1",
+				InstanceType:  aura.InstanceTypeEnterpriseDB,
+			})
+			request.CustomerManagedKeyID = id
+			_, err := client.CreateInstanceFromRequest(request)
+			Expect(err).To(MatchError(ContainSubstring("cannot encrypt instances")))
+		})
+	})
+	Describe("Graph Analytics sessions", func() {
+		It("should be sized, created and deleted", func() {
+			size, err := client.EstimateSessionSize(aura.SessionSizeRequest{
+				NodeCount:           50_000_000,
+				RelationshipCount:   200_000_000,
+				AlgorithmCategories: []aura.AlgorithmCategory{aura.AlgorithmCategoryCentrality},
+			})
+			Expect(err).To(Succeed())
+			Expect(size.Data.DidExceedMaximum).To(BeFalse())
+			id := create()
+			_, err = client.CreateSession(aura.CreateSessionRequest{
+				Name:       "PageRank",
+				InstanceID: id,
+				Memory:     size.Data.RecommendedSize,
+				TTL:        "30m",
+			})
+			Expect(err).To(MatchError(aura.ErrConflict))
+
+			_, err = client.WaitForStatus(ctx, id, []string{aura.StatusRunning}, fast)
+			Expect(err).To(Succeed())
+			created, err := client.CreateSession(aura.CreateSessionRequest{
+				Name:       "PageRank",
+				InstanceID: id,
+				Memory:     size.Data.RecommendedSize,
+				TTL:        "30m",
+			})
+			Expect(err).To(Succeed())
+			Expect(created.Data.Status).To(Equal(aura.SessionStatusCreating))
+			session, err := client.WaitForSession(ctx, created.Data.ID, fast)
+			Expect(err).To(Succeed())
+			Expect(session.Data.Host).NotTo(BeEmpty())
+			list, err := client.ListSessions("", id)
+			Expect(err).To(Succeed())
+			Expect(list.Data).To(ConsistOf(HaveField("Name", "PageRank")))
+
+			Expect(client.DeleteSession(created.Data.ID)).To(Succeed())
+			_, err = client.GetSession(created.Data.ID)
+			Expect(err).To(MatchError(aura.ErrNotFound))
+		})
+		It("should reject unsupported memory and TTLs", func() {
+			id := server.AddInstance(aura.GetResponseData{})
+			_, err := client.CreateSession(aura.CreateSessionRequest{Name: "s", InstanceID: id, Memory: "3GB"})
+			Expect(err).To(MatchError(ContainSubstring("Unsupported session memory")))
+			_, err = client.CreateSession(aura.CreateSessionRequest{Name: "s", InstanceID: id, Memory: "4GB", TTL: "8d"})
+			Expect(err).To(MatchError(aura.ErrValidation))
+		})
+		It("should expire after their TTL", func() {
+			id := server.AddSession(aura.Session{TTL: "10ms"})
+			Eventually(func() bool {
+				_, ok := server.Session(id)
+				return ok
+			}).Should(BeFalse())
+		})
+	})
+	Describe("Metrics integration", func() {
+		It("should expose the metrics of running instances", func() {
+			id := server.AddInstance(aura.GetResponseData{Memory: "8GB", Storage: "16GB"})
+			paused := server.AddInstance(aura.GetResponseData{Status: aura.StatusPaused, Memory: "8GB"})
+			server.SetMetric(id, aura.AuraMetricCPUUsage, 1.5)
+			server.SetMetric(id, aura.AuraMetricQueriesSucceeded, 120)
+			integration, err := client.GetMetricsIntegration(auratest.DefaultTenantID)
+			Expect(err).To(Succeed())
+			metrics, err := client.ScrapeMetrics(integration.Data.Endpoint)
+			Expect(err).To(Succeed())
+			Expect(metrics.Instances).To(HaveKey(id))
+			Expect(metrics.Instances).NotTo(HaveKey(paused))
+			Expect(*metrics.Instances[id]).To(MatchFields(IgnoreExtras, Fields{
+				"CPUUsage":     Equal(1.5),
+				"CPULimit":     Equal(2.0),
+				"StorageLimit": Equal(16.0 * (1 << 30)),
+				"QueryRate":    Equal(120.0),
+			}))
+		})
+		It("should expose the metrics of a single instance", func() {
+			id := server.AddInstance(aura.GetResponseData{Memory: "8GB"})
+			server.AddInstance(aura.GetResponseData{Memory: "8GB"})
+			instance, err := client.GetInstance(id)
+			Expect(err).To(Succeed())
+			metrics, err := client.ScrapeMetrics(instance.Data.MetricsIntegrationURL)
+			Expect(err).To(Succeed())
+			Expect(metrics.Instances).To(HaveLen(1))
+			Expect(metrics.Instances).To(HaveKey(id))
+		})
+		It("should require a token", func() {
+			resp, err := http.Get(server.URL + "/api/v1/" + auratest.DefaultTenantID + "/metrics")
+			Expect(err).To(Succeed())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+		})
+	})
+	Describe("Tenants", func() {
+		It("should include the instance configurations", func() {
+			list, err := client.ListTenants()
+			Expect(err).To(Succeed())
+			Expect(list.Data).To(HaveLen(1))
+			tenant, err := client.GetTenant(auratest.DefaultTenantID)
+			Expect(err).To(Succeed())
+			Expect(tenant.Data.InstanceConfigurations).NotTo(BeEmpty())
+		})
+	})
+	Describe("Authentication", func() {
+		It("should reject unknown credentials", func() {
+			client, err := aura.NewClient(ctx, "foo", "bar", auratest.DefaultTenantID,
+				aura.WithEndpoint(server.URL))
+			Expect(err).To(Succeed())
+			_, err = client.ListTenants()
+			Expect(err).NotTo(Succeed())
+		})
+	})
+	Describe("Faults", func() {
+		It("should fail the given number of requests", func() {
+			client, err := server.Client(ctx, aura.WithRetryPolicy(&aura.StandardRetryPolicy{
+				Retries:  2,
+				Statuses: []int{http.StatusServiceUnavailable},
+				MinWait:  time.Millisecond,
+				MaxWait:  time.Millisecond,
+			}))
+			Expect(err).To(Succeed())
+			server.InjectFault(auratest.Fault{
+				Method: http.MethodGet,
+				Path:   "/v1/tenants",
+				Status: http.StatusServiceUnavailable,
+				Times:  2,
+			})
+			_, err = client.ListTenants()
+			Expect(err).To(Succeed())
+			Expect(server.Requests()).To(ContainElements(
+				auratest.Request{Method: http.MethodPost, Path: "/oauth/token"},
+				auratest.Request{Method: http.MethodGet, Path: "/v1/tenants"},
+			))
+			Expect(server.Requests()).To(HaveLen(4))
+		})
+		It("should throttle requests", func() {
+			client, err := server.Client(ctx, aura.WithRateLimitRetries(0))
+			Expect(err).To(Succeed())
+			server.InjectFault(auratest.Fault{Path: "/v1/*", Status: http.StatusTooManyRequests})
+			_, err = client.ListTenants()
+			Expect(err).To(MatchError(aura.ErrRateLimited))
+			server.ClearFaults()
+			_, err = client.ListTenants()
+			Expect(err).To(Succeed())
+		})
+		It("should add latency", func() {
+			server.InjectFault(auratest.Fault{Path: "/v1/tenants", Latency: time.Second})
+			ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+			defer cancel()
+			_, err := client.ListTenantsWithContext(ctx)
+			Expect(err).To(MatchError(context.DeadlineExceeded))
+		})
+	})
+})
@@ -0,0 +1,108 @@
+package aura
+
+import (
+	"context"
+	"net/http"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// TokenSource supplies the tokens authenticating requests to Aura. Unlike
+// oauth2.TokenSource it is given the context of the request needing the
+// token, so cancelling a request also cancels fetching its token.
+type TokenSource interface {
+	Token(ctx context.Context) (*oauth2.Token, error)
+}
+
+// WithTokenSource sets where the client gets its tokens from instead of
+// fetching them from Aura using the client ID and secret. The client keeps
+// each token until it expires, so the source need not cache them.
+func WithTokenSource(ts TokenSource) Option {
+	return func(c *client) {
+		c.tokenSource = ts
+	}
+}
+
+// clientCredentials fetches tokens from Aura using the client credentials
+// flow, which is how the client gets its tokens by default.
+type clientCredentials struct {
+	conf *clientcredentials.Config
+	base *http.Client
+}
+
+func (s *clientCredentials) Token(ctx context.Context) (*oauth2.Token, error) {
+	// The token request is not the request being authenticated
+	ctx = context.WithValue(ctx, requestKey{}, nil)
+	ctx = context.WithValue(ctx, noRetryKey{}, false)
+	return s.conf.Token(context.WithValue(ctx, oauth2.HTTPClient, s.base))
+}
+
+// tokenTransport authenticates requests with a token from a TokenSource.
+// Unlike oauth2.Transport, tokens are fetched using the context of the
+// request being authenticated.
+type tokenTransport struct {
+	base   http.RoundTripper
+	tokens *tokenCache
+}
+
+// newTokenClient returns an HTTP client authenticating its requests with tokens
+// from source. The authenticated requests are sent using the transport of base.
+func newTokenClient(source TokenSource, base *http.Client) *http.Client {
+	return &http.Client{
+		Transport: &tokenTransport{
+			base: base.Transport,
+			tokens: &tokenCache{
+				sem:   make(chan struct{}, 1),
+				fetch: source.Token,
+			},
+		},
+	}
+}
+
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.tokens.Token(req.Context())
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	authReq := req.Clone(req.Context())
+	token.SetAuthHeader(authReq)
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(authReq)
+}
+
+// tokenCache holds on to a token until it expires. Only one token is fetched
+// at a time, and requests waiting for it give up when their context is done.
+type tokenCache struct {
+	sem   chan struct{}
+	token *oauth2.Token
+	fetch func(ctx context.Context) (*oauth2.Token, error)
+}
+
+// Token returns the cached token if it is still valid and otherwise fetches a new one.
+func (t *tokenCache) Token(ctx context.Context) (*oauth2.Token, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	select {
+	case t.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-t.sem }()
+	if t.token.Valid() {
+		return t.token, nil
+	}
+	token, err := t.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	t.token = token
+	return token, nil
+}
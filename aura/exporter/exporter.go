@@ -0,0 +1,262 @@
+// Package exporter publishes the status of Aura instances as Prometheus
+// metrics, for alerting on instances stuck in "creating" or unexpectedly
+// "paused". An Exporter refreshes the instances from Aura at a fixed interval
+// and serves the last refresh in the Prometheus text format, so scrapes never
+// reach the Aura API however often they happen.
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/indykite/aura-api-client/aura"
+)
+
+// Bounds of the refresh interval. Each refresh lists the instances and gets
+// every one of them, so refreshing faster than MinInterval is refused.
+const (
+	DefaultInterval = time.Minute
+	MinInterval     = 30 * time.Second
+)
+
+// statuses are reported for every instance, so alerts can match a status
+// being 1 as well as it being 0.
+var statuses = []string{
+	aura.StatusCreating,
+	aura.StatusRunning,
+	aura.StatusPausing,
+	aura.StatusPaused,
+	aura.StatusResuming,
+	aura.StatusDestroying,
+	aura.StatusUpdating,
+	aura.StatusRestoring,
+	aura.StatusOverwriting,
+}
+
+// Option customizes an Exporter.
+type Option func(*Exporter)
+
+// WithTenants limits the exporter to the instances of the given tenants.
+// By default all instances the client has access to are exported.
+func WithTenants(ids ...string) Option {
+	return func(e *Exporter) {
+		e.tenants = ids
+	}
+}
+
+// WithInterval sets how often the instances are refreshed from Aura, once a
+// minute by default. Intervals shorter than MinInterval are raised to it.
+func WithInterval(d time.Duration) Option {
+	return func(e *Exporter) {
+		e.interval = max(d, MinInterval)
+	}
+}
+
+// WithLogger sets the logger used for failed refreshes instead of slog.Default.
+func WithLogger(l *slog.Logger) Option {
+	return func(e *Exporter) {
+		e.logger = l
+	}
+}
+
+// Exporter is an http.Handler serving the status of Aura instances.
+type Exporter struct {
+	client   aura.Client
+	tenants  []string
+	interval time.Duration
+	logger   *slog.Logger
+
+	mu          sync.Mutex
+	instances   []aura.GetResponseData
+	lastSuccess time.Time
+	errors      int
+}
+
+// New returns an exporter for the instances available to client. Nothing is
+// exported until the first refresh, see Run and Refresh.
+func New(client aura.Client, options ...Option) *Exporter {
+	e := &Exporter{
+		client:   client,
+		interval: DefaultInterval,
+		logger:   slog.Default(),
+	}
+	for _, o := range options {
+		o(e)
+	}
+	return e
+}
+
+// Run refreshes the instances right away and then at the interval of the
+// exporter, until the context is done. Failed refreshes are logged, and the
+// instances of the last successful refresh are served until the next one.
+func (e *Exporter) Run(ctx context.Context) error {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		if err := e.Refresh(ctx); err != nil && ctx.Err() == nil {
+			e.logger.Warn("Refreshing Aura instances failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh lists the instances from Aura and gets the status, memory and
+// storage of each. Instances destroyed while refreshing are left out.
+func (e *Exporter) Refresh(ctx context.Context) error {
+	instances, err := e.fetch(ctx)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err != nil {
+		e.errors++
+		return err
+	}
+	e.instances = instances
+	e.lastSuccess = time.Now()
+	return nil
+}
+
+func (e *Exporter) fetch(ctx context.Context) ([]aura.GetResponseData, error) {
+	tenants := e.tenants
+	if len(tenants) == 0 {
+		tenants = []string{""}
+	}
+	var instances []aura.GetResponseData
+	for _, tenantID := range tenants {
+		list, err := e.client.ListInstancesWithContext(ctx, tenantID)
+		if err != nil {
+			return nil, err
+		}
+		for _, i := range list.Data {
+			resp, err := e.client.GetInstanceWithContext(ctx, i.ID)
+			if errors.Is(err, aura.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			instances = append(instances, resp.Data)
+		}
+	}
+	return instances, nil
+}
+
+// ServeHTTP writes the metrics of the last refresh in the Prometheus text format.
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = e.WriteMetrics(w)
+}
+
+// WriteMetrics writes the metrics of the last refresh in the Prometheus text format.
+func (e *Exporter) WriteMetrics(w io.Writer) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var b strings.Builder
+	header := func(name, help string) {
+		kind := "gauge"
+		if strings.HasSuffix(name, "_total") {
+			kind = "counter"
+		}
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+
+	header("aura_instance_status", "Whether the instance has the status, 1 for its current status and 0 otherwise.")
+	for _, i := range e.instances {
+		current := slices.Clone(statuses)
+		if !slices.Contains(current, i.Status) {
+			current = append(current, i.Status)
+		}
+		for _, s := range current {
+			sample(&b, "aura_instance_status", labels(i, "status", s), boolValue(s == i.Status))
+		}
+	}
+	header("aura_instance_info", "Type and cloud provider of the instance, always 1.")
+	for _, i := range e.instances {
+		sample(&b, "aura_instance_info", labels(i, "type", i.InstanceType, "cloud_provider", i.CloudProvider), 1)
+	}
+	header("aura_instance_memory_bytes", "Memory allocated to the instance.")
+	for _, i := range e.instances {
+		if n, ok := parseBytes(i.Memory); ok {
+			sample(&b, "aura_instance_memory_bytes", labels(i), n)
+		}
+	}
+	header("aura_instance_storage_bytes", "Storage allocated to the instance.")
+	for _, i := range e.instances {
+		if n, ok := parseBytes(i.Storage); ok {
+			sample(&b, "aura_instance_storage_bytes", labels(i), n)
+		}
+	}
+	header("aura_exporter_last_refresh_timestamp_seconds", "Time of the last successful refresh from Aura.")
+	var last float64
+	if !e.lastSuccess.IsZero() {
+		last = float64(e.lastSuccess.UnixMilli()) / 1000
+	}
+	sample(&b, "aura_exporter_last_refresh_timestamp_seconds", nil, last)
+	header("aura_exporter_refresh_errors_total", "Number of failed refreshes from Aura.")
+	sample(&b, "aura_exporter_refresh_errors_total", nil, float64(e.errors))
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// labels returns the labels identifying the instance followed by the extra labels.
+func labels(i aura.GetResponseData, extra ...string) []string {
+	return append([]string{
+		"tenant_id", i.TenantID,
+		"region", i.Region,
+		"name", i.Name,
+		"instance_id", i.ID,
+	}, extra...)
+}
+
+// sample writes a sample with the given label names and values.
+func sample(b *strings.Builder, name string, labels []string, value float64) {
+	b.WriteString(name)
+	if len(labels) > 0 {
+		b.WriteByte('{')
+		for i := 0; i < len(labels); i += 2 {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(b, `%s="%s"`, labels[i], escape(labels[i+1]))
+		}
+		b.WriteByte('}')
+	}
+	b.WriteByte(' ')
+	b.WriteString(strconv.FormatFloat(value, 'f', -1, 64))
+	b.WriteByte('\n')
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escape(s string) string {
+	return escaper.Replace(s)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// parseBytes parses an amount such as "8GB" reported by Aura.
+func parseBytes(amount string) (float64, bool) {
+	gb, err := strconv.Atoi(strings.TrimSuffix(strings.ToUpper(amount), "GB"))
+	if err != nil {
+		return 0, false
+	}
+	return float64(gb) * (1 << 30), true
+}
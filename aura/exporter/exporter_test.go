@@ -0,0 +1,129 @@
+package exporter_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/indykite/aura-api-client/aura"
+	"github.com/indykite/aura-api-client/aura/auratest"
+	"github.com/indykite/aura-api-client/aura/exporter"
+)
+
+var _ = Describe("Exporter", func() {
+	var (
+		server *auratest.Server
+		client aura.Client
+		ctx    context.Context
+	)
+	BeforeEach(func() {
+		ctx = context.Background()
+		server = auratest.NewServer()
+		DeferCleanup(server.Close)
+		var err error
+		client, err = server.Client(ctx)
+		Expect(err).To(Succeed())
+	})
+	scrape := func(e *exporter.Exporter) string {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("Content-Type")).To(HavePrefix("text/plain; version=0.0.4"))
+		return rec.Body.String()
+	}
+	It("should export the status, memory, storage and type of instances", func() {
+		id := server.AddInstance(aura.GetResponseData{
+			ResponseCommonProperties: aura.ResponseCommonProperties{
+				Name:          "Production",
+				CloudProvider: "gcp",
+				Region:        "europe-west1",
+				InstanceType:  "enterprise-db",
+			},
+			Status:  aura.StatusPaused,
+			Memory:  "8GB",
+			Storage: "16GB",
+		})
+		e := exporter.New(client)
+		Expect(e.Refresh(ctx)).To(Succeed())
+		labels := `tenant_id="auratest-tenant",region="europe-west1",name="Production",instance_id="` + id + `"`
+		Expect(strings.Split(scrape(e), "\n")).To(ContainElements(
+			`aura_instance_status{`+labels+`,status="paused"} 1`,
+			`aura_instance_status{`+labels+`,status="running"} 0`,
+			`aura_instance_status{`+labels+`,status="creating"} 0`,
+			`aura_instance_info{`+labels+`,type="enterprise-db",cloud_provider="gcp"} 1`,
+			`aura_instance_memory_bytes{`+labels+`} 8589934592`,
+			`aura_instance_storage_bytes{`+labels+`} 17179869184`,
+			`aura_exporter_refresh_errors_total 0`,
+		))
+	})
+	It("should only call Aura when refreshing", func() {
+		server.AddInstance(aura.GetResponseData{Memory: "8GB"})
+		e := exporter.New(client)
+		Expect(scrape(e)).NotTo(ContainSubstring("aura_instance_status{"))
+		Expect(e.Refresh(ctx)).To(Succeed())
+		requests := len(server.Requests())
+		for i := 0; i < 10; i++ {
+			Expect(scrape(e)).To(ContainSubstring(`status="running"} 1`))
+		}
+		Expect(server.Requests()).To(HaveLen(requests))
+	})
+	It("should write the Prometheus text format", func() {
+		server.AddInstance(aura.GetResponseData{Memory: "8GB", Storage: "16GB"})
+		e := exporter.New(client)
+		Expect(e.Refresh(ctx)).To(Succeed())
+		samples, err := aura.ParseMetrics(strings.NewReader(scrape(e)))
+		Expect(err).To(Succeed())
+		// A status for each known status, the info, memory, storage and the two exporter samples
+		Expect(samples).To(HaveLen(9 + 5))
+	})
+	It("should keep the last instances when refreshing fails", func() {
+		server.AddInstance(aura.GetResponseData{})
+		e := exporter.New(client)
+		Expect(e.Refresh(ctx)).To(Succeed())
+		server.InjectFault(auratest.Fault{Status: http.StatusBadRequest})
+		Expect(e.Refresh(ctx)).To(MatchError(aura.ErrValidation))
+		metrics := scrape(e)
+		Expect(metrics).To(ContainSubstring(`status="running"} 1`))
+		Expect(metrics).To(ContainSubstring("aura_exporter_refresh_errors_total 1\n"))
+	})
+	It("should only export the instances of the given tenants", func() {
+		fake := &auratest.FakeClient{
+			ListInstancesFunc: func(_ context.Context, tenantID string) (*aura.ListResponse, error) {
+				if tenantID != "mox" {
+					return nil, errors.New("unexpected tenant " + tenantID)
+				}
+				resp := &aura.ListResponse{Data: make([]aura.ListResponseData, 1)}
+				resp.Data[0].ID = "abc123"
+				return resp, nil
+			},
+			GetInstanceFunc: func(_ context.Context, id string) (*aura.GetResponse, error) {
+				resp := &aura.GetResponse{}
+				resp.Data.ID, resp.Data.TenantID, resp.Data.Status = id, "mox", aura.StatusCreating
+				resp.Data.Name = "Escaped \"name\""
+				return resp, nil
+			},
+		}
+		e := exporter.New(fake, exporter.WithTenants("mox"))
+		Expect(e.Refresh(ctx)).To(Succeed())
+		Expect(scrape(e)).To(ContainSubstring(
+			`aura_instance_status{tenant_id="mox",region="",name="Escaped \"name\"",instance_id="abc123",status="creating"} 1`))
+	})
+	It("should refresh until the context is done", func() {
+		server.AddInstance(aura.GetResponseData{})
+		e := exporter.New(client, exporter.WithInterval(time.Millisecond))
+		runCtx, cancel := context.WithCancel(ctx)
+		done := make(chan error)
+		go func() { done <- e.Run(runCtx) }()
+		Eventually(func() string { return scrape(e) }).Should(ContainSubstring(`status="running"} 1`))
+		cancel()
+		Eventually(done).Should(Receive(MatchError(context.Canceled)))
+		// The interval is bounded, so only the initial refresh has happened
+		Expect(server.Requests()).To(HaveLen(3))
+	})
+})
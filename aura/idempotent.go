@@ -0,0 +1,146 @@
+package aura
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// InstanceConflictError is returned when creating an instance using
+// WithIdempotentCreate, and the tenant already has an instance with the same
+// name but a different configuration.
+type InstanceConflictError struct {
+	ID        string // ID of the existing instance
+	Name      string
+	TenantID  string
+	Requested InstanceConfiguration
+	Existing  InstanceConfiguration // Configuration of the existing instance, which lacks the version
+}
+
+func (e *InstanceConflictError) Error() string {
+	var conflicts []string
+	for _, f := range [][3]string{
+		{"cloud_provider", e.Requested.CloudProvider, e.Existing.CloudProvider},
+		{"region", e.Requested.Region, e.Existing.Region},
+		{"memory", e.Requested.Memory, e.Existing.Memory},
+		{"type", e.Requested.InstanceType, e.Existing.InstanceType},
+	} {
+		if f[1] != "" && !strings.EqualFold(f[1], f[2]) {
+			conflicts = append(conflicts, fmt.Sprintf("%s is %q, not %q", f[0], f[2], f[1]))
+		}
+	}
+	return fmt.Sprintf("instance %q already exists in tenant %s as %s with a different configuration: %s",
+		e.Name, e.TenantID, e.ID, strings.Join(conflicts, ", "))
+}
+
+// Is makes an InstanceConflictError match ErrConflict.
+func (e *InstanceConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// WithIdempotentCreate makes creating an instance safe to repeat. Instance names
+// are treated as unique within a tenant: before creating an instance, and after
+// failing in a way that leaves it unknown whether the instance was created, the
+// instances of the tenant are listed. An instance with the requested name and
+// configuration is returned instead of creating a duplicate, without the
+// credentials of its admin user which Aura only returns once. An instance with
+// the requested name but another configuration results in an InstanceConflictError.
+// The request to create the instance is not retried by the retry policy.
+func WithIdempotentCreate() Option {
+	return func(c *client) {
+		c.idempotentCreate = true
+	}
+}
+
+// createInstanceOnce creates the instance unless the tenant already has it.
+func (c *client) createInstanceOnce(ctx context.Context, r CreateInstanceRequest) (*CreateResponse, error) {
+	existing, err := c.findInstance(ctx, r)
+	if err != nil || existing != nil {
+		return existing, err
+	}
+	resp, err := c.createInstance(context.WithValue(ctx, noRetryKey{}, true), r)
+	if err == nil || !isAmbiguous(err) || ctx.Err() != nil {
+		return resp, err
+	}
+	// The instance may have been created even though the request failed
+	existing, findErr := c.findInstance(ctx, r)
+	if findErr != nil || existing == nil {
+		return nil, err
+	}
+	c.logger.Warn("Found the instance after failing to create it", "name", r.Name, "id", existing.Data.ID, "error", err)
+	return existing, nil
+}
+
+// findInstance returns the instance of the tenant with the requested name, or
+// nil if there is none. Instances being destroyed are ignored.
+func (c *client) findInstance(ctx context.Context, r CreateInstanceRequest) (*CreateResponse, error) {
+	list, err := c.ListInstancesWithContext(ctx, r.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	requested := InstanceConfiguration{
+		CloudProvider: string(r.CloudProvider),
+		Region:        r.Region,
+		Memory:        r.Memory,
+		InstanceType:  string(r.InstanceType),
+		Version:       r.Version,
+	}
+	var conflict error
+	for _, instance := range list.Data {
+		if instance.Name != r.Name {
+			continue
+		}
+		// Memory and status are only included when getting a single instance
+		details, err := c.GetInstanceWithContext(ctx, instance.ID)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if details.Data.Status == StatusDestroying {
+			continue
+		}
+		existing := InstanceConfiguration{
+			CloudProvider: instance.CloudProvider,
+			Region:        instance.Region,
+			Memory:        details.Data.Memory,
+			InstanceType:  instance.InstanceType,
+		}
+		if !sameConfiguration(requested, existing) {
+			conflict = &InstanceConflictError{
+				ID:        instance.ID,
+				Name:      r.Name,
+				TenantID:  r.TenantID,
+				Requested: requested,
+				Existing:  existing,
+			}
+			continue
+		}
+		return &CreateResponse{
+			Data: CreateResponseData{ResponseCommonProperties: details.Data.ResponseCommonProperties},
+		}, nil
+	}
+	return nil, conflict
+}
+
+// sameConfiguration reports whether the existing instance has the requested
+// configuration, ignoring the version which Aura does not report.
+func sameConfiguration(requested, existing InstanceConfiguration) bool {
+	return strings.EqualFold(requested.CloudProvider, existing.CloudProvider) &&
+		strings.EqualFold(requested.Region, existing.Region) &&
+		strings.EqualFold(requested.InstanceType, existing.InstanceType) &&
+		(requested.Memory == "" || strings.EqualFold(requested.Memory, existing.Memory))
+}
+
+// isAmbiguous reports whether a request failing with err may still have been
+// carried out by Aura, i.e. because of a server error or a lost connection.
+func isAmbiguous(err error) bool {
+	var auraErr *AuraError
+	if errors.As(err, &auraErr) {
+		// A successful status means only the response could not be read
+		return auraErr.StatusCode >= 500 || auraErr.StatusCode < 300
+	}
+	return !errors.Is(err, ErrValidation)
+}
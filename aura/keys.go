@@ -0,0 +1,241 @@
+package aura
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+)
+
+// CustomerManagedKeyStatus is the status of a customer managed key, which
+// must be ready before instances can be encrypted with it.
+type CustomerManagedKeyStatus string
+
+// Statuses reported by the Aura API for a customer managed key.
+const (
+	CustomerManagedKeyStatusPending  CustomerManagedKeyStatus = "pending"
+	CustomerManagedKeyStatusReady    CustomerManagedKeyStatus = "ready"
+	CustomerManagedKeyStatusError    CustomerManagedKeyStatus = "error"
+	CustomerManagedKeyStatusDeleting CustomerManagedKeyStatus = "deleting"
+)
+
+type CustomerManagedKeySummary struct {
+	ID       string `json:"id"`        // Internal ID of the key
+	Name     string `json:"name"`      // The name we chose for the key
+	TenantID string `json:"tenant_id"` // Tenant the key can be used in
+}
+
+// ListCustomerManagedKeysResponse is returned when listing customer managed keys and is
+// constructed from the values from
+// https://neo4j.com/docs/aura/platform/api/specification/#/customer-managed-keys/get-customer-managed-keys.
+type ListCustomerManagedKeysResponse struct {
+	Data []CustomerManagedKeySummary `json:"data"`
+}
+
+type CustomerManagedKey struct {
+	CustomerManagedKeySummary
+	Status        CustomerManagedKeyStatus `json:"status"`         // pending, ready, error or deleting
+	Created       string                   `json:"created"`        // RFC 3339 time the key was added
+	CloudProvider CloudProvider            `json:"cloud_provider"` // GCP, AWS, ...
+	KeyID         string                   `json:"key_id"`         // ID of the key in the KMS of the cloud provider
+	Region        string                   `json:"region"`         // Region of the instances the key can encrypt
+	InstanceType  InstanceType             `json:"type"`           // Type of the instances the key can encrypt
+}
+
+// CustomerManagedKeyResponse contains information about a given customer managed key
+// and is constructed from specification at
+// https://neo4j.com/docs/aura/platform/api/specification/#/customer-managed-keys/get-customer-managed-key-id.
+type CustomerManagedKeyResponse struct {
+	Data CustomerManagedKey `json:"data"`
+}
+
+// CreateCustomerManagedKeyRequest describes a key held in the KMS of a cloud
+// provider, for Aura to encrypt instances with.
+type CreateCustomerManagedKeyRequest struct {
+	Name          string        `json:"name"`           // The name of the key in Aura
+	TenantID      string        `json:"tenant_id"`      // Defaults to the tenant of the client
+	KeyID         string        `json:"key_id"`         // ID of the key in the KMS, i.e. an AWS ARN
+	CloudProvider CloudProvider `json:"cloud_provider"` // GCP, AWS, ...
+	Region        string        `json:"region"`         // Region of the instances the key can encrypt
+	InstanceType  InstanceType  `json:"type"`           // Type of the instances the key can encrypt
+}
+
+// ListCustomerManagedKeys returns the customer managed keys the client has access to.
+// If a tenant ID is given only keys belonging to that tenant are returned.
+// To specify the context, use ListCustomerManagedKeysWithContext.
+func (c *client) ListCustomerManagedKeys(tenantID string) (*ListCustomerManagedKeysResponse, error) {
+	return c.ListCustomerManagedKeysWithContext(context.Background(), tenantID)
+}
+
+// ListCustomerManagedKeysWithContext is like ListCustomerManagedKeys but uses the given context for the request.
+func (c *client) ListCustomerManagedKeysWithContext(
+	ctx context.Context, tenantID string,
+) (_ *ListCustomerManagedKeysResponse, err error) {
+	ctx, op := c.startOperation(ctx, "ListCustomerManagedKeys", AttributeTenantID.String(tenantID))
+	defer op.end(&err)
+	path := c.api() + "/customer-managed-keys"
+	if tenantID != "" {
+		path += "?" + url.Values{"tenantId": {tenantID}}.Encode()
+	}
+	req, err := c.newRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, newAuraError(errors.New(resp.Status), resp)
+	}
+
+	var listResp ListCustomerManagedKeysResponse
+	err = json.NewDecoder(resp.Body).Decode(&listResp)
+	if err != nil {
+		return nil, newAuraError(err, resp)
+	}
+
+	return &listResp, nil
+}
+
+// CreateCustomerManagedKey adds a key from the KMS of a cloud provider to Aura.
+// Aura needs access to the key, and the returned key is "pending" until Aura
+// has verified it, see WaitForCustomerManagedKey. Once ready it can be used by
+// setting CustomerManagedKeyID when creating instances.
+// To specify the context, use CreateCustomerManagedKeyWithContext.
+func (c *client) CreateCustomerManagedKey(r CreateCustomerManagedKeyRequest) (*CustomerManagedKeyResponse, error) {
+	return c.CreateCustomerManagedKeyWithContext(context.Background(), r)
+}
+
+// CreateCustomerManagedKeyWithContext is like CreateCustomerManagedKey but uses the given context for the request.
+func (c *client) CreateCustomerManagedKeyWithContext(
+	ctx context.Context, r CreateCustomerManagedKeyRequest,
+) (_ *CustomerManagedKeyResponse, err error) {
+	if r.TenantID == "" {
+		r.TenantID = c.tenantID
+	}
+	ctx, op := c.startOperation(ctx, "CreateCustomerManagedKey", AttributeTenantID.String(r.TenantID))
+	defer op.end(&err)
+	req, err := c.newRequest(ctx, "POST", c.api()+"/customer-managed-keys", r)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, newAuraError(errors.New(resp.Status), resp)
+	}
+
+	var createResp CustomerManagedKeyResponse
+	err = json.NewDecoder(resp.Body).Decode(&createResp)
+	if err != nil {
+		return nil, newAuraError(err, resp)
+	}
+
+	op.span.SetAttributes(AttributeCustomerManagedKeyID.String(createResp.Data.ID))
+	return &createResp, nil
+}
+
+// GetCustomerManagedKey returns the customer managed key with the given ID.
+// To specify the context, use GetCustomerManagedKeyWithContext.
+func (c *client) GetCustomerManagedKey(id string) (*CustomerManagedKeyResponse, error) {
+	return c.GetCustomerManagedKeyWithContext(context.Background(), id)
+}
+
+// GetCustomerManagedKeyWithContext is like GetCustomerManagedKey but uses the given context for the request.
+func (c *client) GetCustomerManagedKeyWithContext(
+	ctx context.Context, id string,
+) (_ *CustomerManagedKeyResponse, err error) {
+	ctx, op := c.startOperation(ctx, "GetCustomerManagedKey", AttributeCustomerManagedKeyID.String(id))
+	defer op.end(&err)
+	req, err := c.newRequest(ctx, "GET", c.api()+"/customer-managed-keys/"+id, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, newAuraError(errors.New(resp.Status), resp)
+	}
+
+	var getResp CustomerManagedKeyResponse
+	err = json.NewDecoder(resp.Body).Decode(&getResp)
+	if err != nil {
+		return nil, newAuraError(err, resp)
+	}
+
+	return &getResp, nil
+}
+
+// DeleteCustomerManagedKey removes a customer managed key from Aura. The key
+// itself is left in the KMS of the cloud provider.
+// A 404 from the API is seen as successful as it indicates the key no longer exists.
+// To specify the context, use DeleteCustomerManagedKeyWithContext.
+func (c *client) DeleteCustomerManagedKey(id string) error {
+	return c.DeleteCustomerManagedKeyWithContext(context.Background(), id)
+}
+
+// DeleteCustomerManagedKeyWithContext is like DeleteCustomerManagedKey but uses the given context for the request.
+func (c *client) DeleteCustomerManagedKeyWithContext(ctx context.Context, id string) (err error) {
+	ctx, op := c.startOperation(ctx, "DeleteCustomerManagedKey", AttributeCustomerManagedKeyID.String(id))
+	defer op.end(&err)
+	req, err := c.newRequest(ctx, "DELETE", c.api()+"/customer-managed-keys/"+id, nil)
+	if err != nil {
+		return err
+	}
+	apiResp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer apiResp.Body.Close()
+	if apiResp.StatusCode == http.StatusNotFound ||
+		(apiResp.StatusCode >= http.StatusOK && apiResp.StatusCode < http.StatusMultipleChoices) {
+		return nil
+	}
+	return newAuraError(errors.New(apiResp.Status), apiResp)
+}
+
+// WaitForCustomerManagedKey polls the key until it is ready, returning the last
+// response from Aura. A FailureStatusError is returned if the key reaches the
+// status "error" or "deleting", or any status given using WithFailureStatuses.
+// The context and WithWaitTimeout bound the total time spent waiting, after
+// which a WaitTimeoutError is returned.
+func (c *client) WaitForCustomerManagedKey(
+	ctx context.Context, id string, options ...WaitOption,
+) (_ *CustomerManagedKeyResponse, err error) {
+	ctx, op := c.startOperation(ctx, "WaitForCustomerManagedKey", AttributeCustomerManagedKeyID.String(id))
+	defer op.end(&err)
+	conf := newWaitConfig(options)
+	conf.failureStatuses = slices.Clone(conf.failureStatuses)
+	for _, s := range []CustomerManagedKeyStatus{CustomerManagedKeyStatusError, CustomerManagedKeyStatusDeleting} {
+		if !slices.Contains(conf.failureStatuses, string(s)) {
+			conf.failureStatuses = append(conf.failureStatuses, string(s))
+		}
+	}
+	var last *CustomerManagedKeyResponse
+	targets := []string{string(CustomerManagedKeyStatusReady)}
+	err = poll(ctx, id, targets, conf, func(ctx context.Context) (string, error) {
+		resp, err := c.GetCustomerManagedKeyWithContext(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		last = resp
+		return string(resp.Data.Status), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return last, nil
+}
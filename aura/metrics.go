@@ -0,0 +1,266 @@
+package aura
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Names of the metrics exposed by the metrics integration of Aura, which are
+// summarised in InstanceMetrics. Aura samples each metric over a minute, and
+// reports its minimum, maximum and average using the "aggregation" label.
+const (
+	AuraMetricCPUUsage         = "neo4j_aura_cpu_usage"                   // CPU cores used
+	AuraMetricCPULimit         = "neo4j_aura_cpu_limit"                   // CPU cores available
+	AuraMetricHeapUsedRatio    = "neo4j_dbms_vm_heap_used_ratio"          // Fraction of the heap in use
+	AuraMetricStoreSize        = "neo4j_database_store_size_database"     // Bytes used by the store of a database
+	AuraMetricStorageLimit     = "neo4j_aura_storage_limit"               // Bytes of storage available
+	AuraMetricQueriesSucceeded = "neo4j_db_query_execution_success_total" // Queries which succeeded in the minute
+	AuraMetricQueriesFailed    = "neo4j_db_query_execution_failure_total" // Queries which failed in the minute
+)
+
+// metricsHost serves the metrics integration of Aura.
+const metricsHost = "customer-metrics-api.neo4j.io"
+
+// Labels of the samples exposed by the metrics integration.
+const (
+	LabelInstanceID  = "instance_id"
+	LabelDatabase    = "database"
+	LabelAggregation = "aggregation"
+)
+
+// MetricSample is a single sample read from a Prometheus endpoint.
+type MetricSample struct {
+	Name      string
+	Labels    map[string]string
+	Value     float64
+	Timestamp time.Time // Zero unless the endpoint gave the time of the sample
+}
+
+// InstanceMetrics summarises the samples of an instance. Values are the
+// maximum over the minute sampled and, for metrics reported per database,
+// the sum over the databases of the instance.
+type InstanceMetrics struct {
+	InstanceID       string
+	CPUUsage         float64 // CPU cores used
+	CPULimit         float64 // CPU cores available
+	HeapUsedRatio    float64 // Fraction of the heap in use, between 0 and 1
+	StoreSize        float64 // Bytes used by the stores of the databases
+	StorageLimit     float64 // Bytes of storage available
+	QueryRate        float64 // Successful queries per minute
+	FailedQueryRate  float64 // Failed queries per minute
+	LatestSampleTime time.Time
+}
+
+// Metrics holds the samples scraped from a metrics integration endpoint.
+type Metrics struct {
+	Samples   []MetricSample
+	Instances map[string]*InstanceMetrics // Summary of the samples by instance ID
+}
+
+// ScrapeMetrics fetches the metrics from a metrics integration endpoint, as
+// returned by GetMetricsIntegration or found in the MetricsIntegrationURL of
+// an instance. The endpoint is authenticated with the token of the client, so
+// endpoints on hosts other than the metrics integration of Aura and the
+// endpoint of the client are refused. Aura updates the metrics once a minute,
+// so there is no point in scraping more often than that.
+// To specify the context, use ScrapeMetricsWithContext.
+func (c *client) ScrapeMetrics(endpoint string) (*Metrics, error) {
+	return c.ScrapeMetricsWithContext(context.Background(), endpoint)
+}
+
+// ScrapeMetricsWithContext is like ScrapeMetrics but uses the given context for the request.
+func (c *client) ScrapeMetricsWithContext(ctx context.Context, endpoint string) (_ *Metrics, err error) {
+	ctx, op := c.startOperation(ctx, "ScrapeMetrics")
+	defer op.end(&err)
+	if err := c.checkMetricsEndpoint(endpoint); err != nil {
+		return nil, err
+	}
+	req, err := c.newRequest(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/plain;version=0.0.4")
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, newAuraError(errors.New(resp.Status), resp)
+	}
+
+	samples, err := ParseMetrics(resp.Body)
+	if err != nil {
+		return nil, newAuraError(err, resp)
+	}
+
+	return &Metrics{Samples: samples, Instances: summarise(samples)}, nil
+}
+
+// checkMetricsEndpoint returns an error unless the endpoint is served by Aura,
+// so the token of the client is not sent anywhere else.
+func (c *client) checkMetricsEndpoint(endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return err
+	}
+	if u.Scheme == "https" && u.Host == metricsHost {
+		return nil
+	}
+	if api, err := url.Parse(c.endpoint); err == nil && u.Scheme == api.Scheme && u.Host == api.Host {
+		return nil
+	}
+	return fmt.Errorf("refusing to send the token of the client to %s://%s, which is not a metrics endpoint of Aura",
+		u.Scheme, u.Host)
+}
+
+// ParseMetrics reads samples in the Prometheus text exposition format.
+// Comments, including HELP and TYPE lines, are skipped.
+func ParseMetrics(r io.Reader) ([]MetricSample, error) {
+	var samples []MetricSample
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		sample, err := parseSample(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sample on line %d: %w", n, err)
+		}
+		samples = append(samples, sample)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return samples, nil
+}
+
+// parseSample parses a line such as `name{label="value"} 1.5 1706702400000`.
+func parseSample(line string) (MetricSample, error) {
+	sample := MetricSample{Labels: map[string]string{}}
+	end := strings.IndexAny(line, "{ \t")
+	if end <= 0 {
+		return sample, errors.New("missing value")
+	}
+	sample.Name, line = line[:end], line[end:]
+	if strings.HasPrefix(line, "{") {
+		var err error
+		if line, err = parseLabels(line[1:], sample.Labels); err != nil {
+			return sample, err
+		}
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 || len(fields) > 2 {
+		return sample, fmt.Errorf("expected a value and optional timestamp, got %q", line)
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return sample, err
+	}
+	sample.Value = value
+	if len(fields) == 2 {
+		ms, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return sample, err
+		}
+		sample.Timestamp = time.UnixMilli(ms)
+	}
+	return sample, nil
+}
+
+// parseLabels parses the labels following the opening brace into labels,
+// returning the rest of the line after the closing brace.
+func parseLabels(line string, labels map[string]string) (string, error) {
+	for {
+		line = strings.TrimLeft(line, " \t,")
+		if rest, ok := strings.CutPrefix(line, "}"); ok {
+			return rest, nil
+		}
+		name, rest, ok := strings.Cut(line, "=")
+		if !ok || !strings.HasPrefix(rest, `"`) {
+			return "", fmt.Errorf("invalid label in %q", line)
+		}
+		name = strings.TrimSpace(name)
+		var value strings.Builder
+		i := 1
+		for ; i < len(rest) && rest[i] != '"'; i++ {
+			if rest[i] != '\\' || i+1 == len(rest) {
+				value.WriteByte(rest[i])
+				continue
+			}
+			i++
+			switch rest[i] {
+			case 'n':
+				value.WriteByte('\n')
+			default:
+				value.WriteByte(rest[i])
+			}
+		}
+		if i == len(rest) {
+			return "", fmt.Errorf("unterminated value of label %s", name)
+		}
+		labels[name] = value.String()
+		line = rest[i+1:]
+	}
+}
+
+// summarise groups the samples by instance, using the maximum of each metric
+// when Aura reports several aggregations.
+func summarise(samples []MetricSample) map[string]*InstanceMetrics {
+	instances := make(map[string]*InstanceMetrics)
+	// Metrics reported per database are summed over the databases
+	perDatabase := make(map[[3]string]float64)
+	for _, s := range samples {
+		id := s.Labels[LabelInstanceID]
+		if id == "" {
+			continue
+		}
+		if a, ok := s.Labels[LabelAggregation]; ok && !strings.EqualFold(a, "MAX") {
+			continue
+		}
+		m := instances[id]
+		if m == nil {
+			m = &InstanceMetrics{InstanceID: id}
+			instances[id] = m
+		}
+		if s.Timestamp.After(m.LatestSampleTime) {
+			m.LatestSampleTime = s.Timestamp
+		}
+		switch s.Name {
+		case AuraMetricCPUUsage:
+			m.CPUUsage = math.Max(m.CPUUsage, s.Value)
+		case AuraMetricCPULimit:
+			m.CPULimit = math.Max(m.CPULimit, s.Value)
+		case AuraMetricHeapUsedRatio:
+			m.HeapUsedRatio = math.Max(m.HeapUsedRatio, s.Value)
+		case AuraMetricStorageLimit:
+			m.StorageLimit = math.Max(m.StorageLimit, s.Value)
+		case AuraMetricStoreSize, AuraMetricQueriesSucceeded, AuraMetricQueriesFailed:
+			key := [3]string{id, s.Name, s.Labels[LabelDatabase]}
+			perDatabase[key] = math.Max(perDatabase[key], s.Value)
+		}
+	}
+	for key, value := range perDatabase {
+		m := instances[key[0]]
+		switch key[1] {
+		case AuraMetricStoreSize:
+			m.StoreSize += value
+		case AuraMetricQueriesSucceeded:
+			m.QueryRate += value
+		case AuraMetricQueriesFailed:
+			m.FailedQueryRate += value
+		}
+	}
+	return instances
+}
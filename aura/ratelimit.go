@@ -0,0 +1,173 @@
+package aura
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	rateLimitRetries      = 3
+	defaultRetryAfterWait = time.Second
+	defaultMaxRetryAfter  = time.Minute
+)
+
+// RateLimitStats describes how much callers of a client have been held back,
+// either by the client-side limiter set using WithRateLimit or by Aura
+// responding with 429 Too Many Requests.
+type RateLimitStats struct {
+	Requests       int64         // Requests sent through the client
+	Delayed        int64         // Requests that had to wait for the client-side limiter
+	LimiterWait    time.Duration // Total time spent waiting for the client-side limiter
+	MaxLimiterWait time.Duration // Longest time a single request waited for the limiter
+	Throttled      int64         // Responses from Aura with status 429
+	RetryAfterWait time.Duration // Total time spent waiting before retrying throttled requests
+}
+
+// rateLimiter holds the client-side limiter shared by all goroutines using
+// a client, along with the statistics of how long they have waited.
+type rateLimiter struct {
+	limiter *rate.Limiter
+	mu      sync.Mutex
+	stats   RateLimitStats
+}
+
+// WithRateLimit limits the client to sending on average requestsPerSecond
+// requests per second, allowing bursts of up to burst requests. Requests
+// exceeding the limit wait until they are allowed or their context is done.
+// The limit is shared by everything using the client.
+func WithRateLimit(requestsPerSecond float64, burst int) Option {
+	return func(c *client) {
+		c.rateLimiter.limiter = rate.NewLimiter(rate.Limit(requestsPerSecond), burst)
+	}
+}
+
+// WithRateLimitRetries sets how many times a request is retried when Aura
+// responds with 429 Too Many Requests. Before retrying the client waits as
+// long as instructed by the Retry-After header, see WithMaxRetryAfter. By
+// default requests are retried 3 times, and 0 disables retrying.
+func WithRateLimitRetries(n int) Option {
+	return func(c *client) {
+		c.rateLimitRetries = n
+	}
+}
+
+// WithMaxRetryAfter sets the longest a throttled request waits before being
+// retried, one minute by default. When Aura asks to wait longer the request
+// fails right away with the 429 response, leaving it to the caller to decide
+// whether to wait.
+func WithMaxRetryAfter(d time.Duration) Option {
+	return func(c *client) {
+		c.maxRetryAfter = d
+	}
+}
+
+// RateLimitStats returns how much the callers of the client have been held back so far.
+func (c *client) RateLimitStats() RateLimitStats {
+	c.rateLimiter.mu.Lock()
+	defer c.rateLimiter.mu.Unlock()
+	return c.rateLimiter.stats
+}
+
+// wait blocks until the client-side limiter allows another request.
+func (r *rateLimiter) wait(ctx context.Context) error {
+	var waited time.Duration
+	if r.limiter != nil {
+		start := time.Now()
+		if err := r.limiter.Wait(ctx); err != nil {
+			return err
+		}
+		waited = time.Since(start)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stats.Requests++
+	// Waits shorter than this are only the overhead of asking the limiter
+	if waited > time.Millisecond {
+		r.stats.Delayed++
+		r.stats.LimiterWait += waited
+		r.stats.MaxLimiterWait = max(r.stats.MaxLimiterWait, waited)
+	}
+	return nil
+}
+
+// throttled records that Aura responded with 429 and waits as instructed.
+func (r *rateLimiter) throttled(ctx context.Context, wait time.Duration) error {
+	r.mu.Lock()
+	r.stats.Throttled++
+	r.stats.RetryAfterWait += wait
+	r.mu.Unlock()
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// doRateLimited sends the request once the limiter allows it, retrying it
+// when Aura responds with 429 Too Many Requests.
+func (c *client) doRateLimited(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if err := c.rateLimiter.wait(req.Context()); err != nil {
+			return nil, err
+		}
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusTooManyRequests || attempt >= c.rateLimitRetries {
+			return resp, nil
+		}
+		wait := retryAfter(resp, attempt)
+		if wait > c.maxRetryAfter {
+			return resp, nil
+		}
+		// Drain the body so the connection can be reused
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if err = c.rateLimiter.throttled(req.Context(), wait); err != nil {
+			return nil, err
+		}
+		recordRetry(req.Context())
+		req, err = rewind(req)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// retryAfter returns how long to wait before retrying a throttled request
+// according to the Retry-After header, which is either a number of seconds
+// or a date. Without the header the wait doubles with each attempt.
+func retryAfter(resp *http.Response, attempt int) time.Duration {
+	header := resp.Header.Get("Retry-After")
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		return max(time.Until(date), 0)
+	}
+	return defaultRetryAfterWait << attempt
+}
+
+// rewind returns a copy of the request with a fresh body so it can be sent again.
+func rewind(req *http.Request) (*http.Request, error) {
+	r := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = body
+	}
+	return r, nil
+}
@@ -0,0 +1,307 @@
+package reconcile
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/indykite/aura-api-client/aura"
+)
+
+// Action is a change made to an instance.
+type Action string
+
+const (
+	ActionCreate  Action = "create"
+	ActionResize  Action = "resize"
+	ActionPause   Action = "pause"
+	ActionResume  Action = "resume"
+	ActionDestroy Action = "destroy"
+)
+
+// InstanceState is a live instance as it was when planning.
+type InstanceState struct {
+	ID            string `json:"id"`
+	Status        string `json:"status"`
+	CloudProvider string `json:"cloud_provider"`
+	Region        string `json:"region"`
+	InstanceType  string `json:"type"`
+	Memory        string `json:"memory"`
+	Storage       string `json:"storage"`
+}
+
+func stateOf(i *aura.GetResponseData) *InstanceState {
+	return &InstanceState{
+		ID:            i.ID,
+		Status:        i.Status,
+		CloudProvider: i.CloudProvider,
+		Region:        i.Region,
+		InstanceType:  i.InstanceType,
+		Memory:        i.Memory,
+		Storage:       i.Storage,
+	}
+}
+
+// FieldChange is a field of an instance which is changed, with its current
+// value, which is empty for new instances, and desired value, which is empty
+// for destroyed instances.
+type FieldChange struct {
+	Field   string `json:"field"`
+	Current string `json:"current"`
+	Desired string `json:"desired"`
+}
+
+// Change is a single action on an instance. An instance may need several
+// changes, i.e. resuming it before resizing it, which are applied in order.
+type Change struct {
+	Action      Action         `json:"action"`
+	Name        string         `json:"name"`
+	TenantID    string         `json:"tenant_id"`
+	InstanceID  string         `json:"instance_id,omitempty"` // Empty until the instance has been created
+	Current     *InstanceState `json:"current,omitempty"`     // The live instance, nil if it did not exist
+	Desired     InstanceSpec   `json:"desired"`               // Empty when destroying instances not in the spec
+	Fields      []FieldChange  `json:"fields"`
+	Destructive bool           `json:"destructive"` // Whether the data of the instance is lost
+	Billable    bool           `json:"billable"`    // Whether Aura starts charging, or charges more, for the instance
+	Reason      string         `json:"reason"`      // Why the change is needed
+}
+
+// Plan is the list of changes needed to make the live instances match a spec.
+// It can be stored as JSON, and applied later unless the instances it changes
+// have drifted from how they were when planning.
+type Plan struct {
+	Changes []Change `json:"changes"`
+}
+
+// ReadPlan reads a plan stored as JSON.
+func ReadPlan(r io.Reader) (*Plan, error) {
+	var plan Plan
+	if err := json.NewDecoder(r).Decode(&plan); err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// WriteJSON writes the plan as JSON, which can be read back using ReadPlan.
+func (p *Plan) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(p)
+}
+
+// Destructive returns the changes of the plan which lose data.
+func (p *Plan) Destructive() []Change {
+	var changes []Change
+	for _, c := range p.Changes {
+		if c.Destructive {
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+// WriteText writes the plan for people to review, listing the changes with
+// the fields they change.
+func (p *Plan) WriteText(w io.Writer) error {
+	var b strings.Builder
+	if len(p.Changes) == 0 {
+		b.WriteString("No changes, the instances match the spec.\n")
+	}
+	counts := make(map[Action]int)
+	for _, c := range p.Changes {
+		counts[c.Action]++
+		id := c.InstanceID
+		if id == "" {
+			id = "new"
+		}
+		var flags []string
+		if c.Destructive {
+			flags = append(flags, "destructive")
+		}
+		if c.Billable {
+			flags = append(flags, "billable")
+		}
+		fmt.Fprintf(&b, "%s %s %s (%s) in tenant %s", symbol(c.Action), c.Action, c.Name, id, c.TenantID)
+		if len(flags) > 0 {
+			fmt.Fprintf(&b, " [%s]", strings.Join(flags, ", "))
+		}
+		fmt.Fprintf(&b, "\n    # %s\n", c.Reason)
+		for _, f := range c.Fields {
+			switch {
+			case f.Current == "":
+				fmt.Fprintf(&b, "    %s: %s\n", f.Field, f.Desired)
+			case f.Desired == "":
+				fmt.Fprintf(&b, "    %s: %s -> (none)\n", f.Field, f.Current)
+			default:
+				fmt.Fprintf(&b, "    %s: %s -> %s\n", f.Field, f.Current, f.Desired)
+			}
+		}
+	}
+	if len(p.Changes) > 0 {
+		var summary []string
+		for _, a := range []Action{ActionCreate, ActionResize, ActionPause, ActionResume, ActionDestroy} {
+			if counts[a] > 0 {
+				summary = append(summary, fmt.Sprintf("%d to %s", counts[a], a))
+			}
+		}
+		fmt.Fprintf(&b, "\nPlan: %s.\n", strings.Join(summary, ", "))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (p *Plan) String() string {
+	var b strings.Builder
+	_ = p.WriteText(&b)
+	return b.String()
+}
+
+func symbol(a Action) string {
+	switch a {
+	case ActionCreate:
+		return "+"
+	case ActionDestroy:
+		return "-"
+	default:
+		return "~"
+	}
+}
+
+// describe fills in the fields changed by c and whether it is destructive or billable.
+func (c *Change) describe() {
+	desired := c.Desired
+	switch c.Action {
+	case ActionCreate:
+		c.Fields = []FieldChange{
+			{Field: "cloud_provider", Desired: desired.CloudProvider},
+			{Field: "region", Desired: desired.Region},
+			{Field: "type", Desired: desired.InstanceType},
+			{Field: "memory", Desired: desired.Memory},
+			{Field: "version", Desired: desired.Version},
+		}
+		c.Billable = true
+	case ActionResize:
+		c.Fields = []FieldChange{{Field: "memory", Current: c.Current.Memory, Desired: desired.Memory}}
+		c.Billable = gigabytes(desired.Memory) > gigabytes(c.Current.Memory)
+	case ActionPause:
+		c.Fields = []FieldChange{{Field: "state", Current: StateRunning, Desired: StatePaused}}
+	case ActionResume:
+		c.Fields = []FieldChange{{Field: "state", Current: StatePaused, Desired: StateRunning}}
+		c.Billable = true
+	case ActionDestroy:
+		c.Fields = []FieldChange{
+			{Field: "cloud_provider", Current: c.Current.CloudProvider},
+			{Field: "region", Current: c.Current.Region},
+			{Field: "type", Current: c.Current.InstanceType},
+			{Field: "memory", Current: c.Current.Memory},
+		}
+		c.Destructive = true
+	}
+}
+
+// gigabytes parses an amount such as "8GB", returning 0 if it cannot be parsed.
+func gigabytes(amount string) int {
+	gb, _ := strconv.Atoi(strings.TrimSuffix(strings.ToUpper(amount), "GB"))
+	return gb
+}
+
+// Drift is a difference between an instance as it was when planning and as it is now.
+type Drift struct {
+	Name       string
+	TenantID   string
+	InstanceID string // The instance which changed, or was created with the name of one the plan creates
+	Field      string
+	Planned    string
+	Live       string
+}
+
+// DriftError is returned when applying a plan after the instances it changes
+// have changed. Nothing has been applied, and the plan should be made again.
+type DriftError struct {
+	Drifts []Drift
+}
+
+func (e *DriftError) Error() string {
+	drifts := make([]string, 0, len(e.Drifts))
+	for _, d := range e.Drifts {
+		drifts = append(drifts, fmt.Sprintf("%s %s was %q when planning but is now %q",
+			d.Name, d.Field, d.Planned, d.Live))
+	}
+	return "the instances have changed since planning: " + strings.Join(drifts, ", ")
+}
+
+// checkDrift compares the instances changed by the plan with the live instances.
+func checkDrift(ctx context.Context, client aura.Client, plan *Plan) error {
+	var drifts []Drift
+	checked := make(map[string]bool)
+	destroyed := make(map[string]bool)
+	type key struct{ tenant, name string }
+	creates := make(map[key]bool)
+	for _, c := range plan.Changes {
+		if c.Action == ActionCreate {
+			creates[key{c.TenantID, c.Name}] = true
+			continue
+		}
+		if c.Current == nil || checked[c.Current.ID] {
+			continue
+		}
+		checked[c.Current.ID] = true
+		if c.Action == ActionDestroy {
+			destroyed[c.Current.ID] = true
+		}
+		drift := Drift{Name: c.Name, TenantID: c.TenantID, InstanceID: c.Current.ID}
+		resp, err := client.GetInstanceWithContext(ctx, c.Current.ID)
+		if errors.Is(err, aura.ErrNotFound) {
+			drift.Field, drift.Planned, drift.Live = "status", c.Current.Status, "(none)"
+			drifts = append(drifts, drift)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		live := stateOf(&resp.Data)
+		for _, f := range [][3]string{
+			{"state", currentState(c.Current.Status), currentState(live.Status)},
+			{"cloud_provider", c.Current.CloudProvider, live.CloudProvider},
+			{"region", c.Current.Region, live.Region},
+			{"type", c.Current.InstanceType, live.InstanceType},
+			{"memory", c.Current.Memory, live.Memory},
+		} {
+			if !strings.EqualFold(f[1], f[2]) {
+				drift.Field, drift.Planned, drift.Live = f[0], f[1], f[2]
+				drifts = append(drifts, drift)
+			}
+		}
+	}
+	// Instances created since planning with the names of planned instances
+	tenants := make(map[string]bool)
+	for k := range creates {
+		tenants[k.tenant] = true
+	}
+	for tenantID := range tenants {
+		live, err := liveInstances(ctx, client, tenantID)
+		if err != nil {
+			return err
+		}
+		for name, i := range live {
+			if creates[key{tenantID, name}] && !destroyed[i.ID] {
+				drifts = append(drifts, Drift{
+					Name:       name,
+					TenantID:   tenantID,
+					InstanceID: i.ID,
+					Field:      "status",
+					Planned:    "(none)",
+					Live:       i.Status,
+				})
+			}
+		}
+	}
+	if len(drifts) > 0 {
+		return &DriftError{Drifts: drifts}
+	}
+	return nil
+}
@@ -0,0 +1,338 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/indykite/aura-api-client/aura"
+)
+
+// DestructiveChangeError is returned when applying a plan which would destroy
+// instances, without allowing it using AllowDestroy.
+type DestructiveChangeError struct {
+	Changes []Change
+}
+
+func (e *DestructiveChangeError) Error() string {
+	names := make([]string, 0, len(e.Changes))
+	for _, c := range e.Changes {
+		names = append(names, c.Name)
+	}
+	return "the plan destroys the instances " + strings.Join(names, ", ") + ", which has not been allowed"
+}
+
+// Option customizes how a plan is applied.
+type Option func(*config)
+
+type config struct {
+	allowDestroy bool
+	dryRun       bool
+	waitOptions  []aura.WaitOption
+}
+
+// AllowDestroy allows destroying instances, which is refused by default.
+func AllowDestroy() Option {
+	return func(c *config) {
+		c.allowDestroy = true
+	}
+}
+
+// DryRun makes Reconcile only plan the changes without applying them.
+func DryRun() Option {
+	return func(c *config) {
+		c.dryRun = true
+	}
+}
+
+// WithWaitOptions sets the options used when waiting for instances to reach
+// the status needed for the next change.
+func WithWaitOptions(options ...aura.WaitOption) Option {
+	return func(c *config) {
+		c.waitOptions = options
+	}
+}
+
+// Reconcile plans the changes needed to make the live instances match the
+// spec and applies them, unless DryRun is given. The plan is returned along
+// with any error, so it shows which changes were planned.
+func Reconcile(ctx context.Context, client aura.Client, spec *Spec, options ...Option) (*Plan, error) {
+	plan, err := NewPlan(ctx, client, spec)
+	if err != nil {
+		return nil, err
+	}
+	conf := newConfig(options)
+	if conf.dryRun {
+		return plan, nil
+	}
+	return plan, Apply(ctx, client, plan, options...)
+}
+
+func newConfig(options []Option) config {
+	var conf config
+	for _, o := range options {
+		o(&conf)
+	}
+	return conf
+}
+
+// NewPlan compares the spec with the live instances of its tenants, returning
+// the changes needed to make them match.
+func NewPlan(ctx context.Context, client aura.Client, spec *Spec) (*Plan, error) {
+	plan := &Plan{}
+	for _, tenantID := range spec.tenants() {
+		live, err := liveInstances(ctx, client, tenantID)
+		if err != nil {
+			return nil, err
+		}
+		listed := make(map[string]bool)
+		for _, desired := range spec.Instances {
+			if desired.TenantID != tenantID {
+				continue
+			}
+			listed[desired.Name] = true
+			plan.Changes = append(plan.Changes, planInstance(desired, live[desired.Name])...)
+		}
+		if !spec.Prune {
+			continue
+		}
+		for _, name := range sortedNames(live) {
+			if !listed[name] {
+				c := Change{
+					Action:     ActionDestroy,
+					Name:       name,
+					TenantID:   tenantID,
+					InstanceID: live[name].ID,
+					Current:    stateOf(live[name]),
+					Reason:     "not in the spec",
+				}
+				c.describe()
+				plan.Changes = append(plan.Changes, c)
+			}
+		}
+	}
+	return plan, nil
+}
+
+// liveInstances returns the instances of the tenant by name, leaving out
+// instances being destroyed.
+func liveInstances(ctx context.Context, client aura.Client, tenantID string) (map[string]*aura.GetResponseData, error) {
+	list, err := client.ListInstancesWithContext(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	live := make(map[string]*aura.GetResponseData)
+	for _, i := range list.Data {
+		// Memory and status are only included when getting a single instance
+		resp, err := client.GetInstanceWithContext(ctx, i.ID)
+		if errors.Is(err, aura.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if resp.Data.Status == aura.StatusDestroying {
+			continue
+		}
+		if existing, ok := live[i.Name]; ok {
+			return nil, fmt.Errorf("tenant %s has more than one instance named %s: %s and %s",
+				tenantID, i.Name, existing.ID, i.ID)
+		}
+		live[i.Name] = &resp.Data
+	}
+	return live, nil
+}
+
+// planInstance returns the changes making the live instance, which is nil if
+// it does not exist, match the desired instance.
+func planInstance(desired InstanceSpec, live *aura.GetResponseData) []Change {
+	change := func(action Action, reason string) Change {
+		c := Change{Action: action, Name: desired.Name, TenantID: desired.TenantID, Desired: desired, Reason: reason}
+		if live != nil {
+			c.InstanceID = live.ID
+			c.Current = stateOf(live)
+		}
+		c.describe()
+		return c
+	}
+	if desired.State == StateAbsent {
+		if live == nil {
+			return nil
+		}
+		return []Change{change(ActionDestroy, "the spec says the instance should be absent")}
+	}
+	if live == nil {
+		return create(desired, "the instance does not exist")
+	}
+	if field, current, wanted := immutableDifference(desired, live); field != "" {
+		return append(
+			[]Change{change(ActionDestroy, fmt.Sprintf("replacing it to change the %s from %s to %s",
+				field, current, wanted))},
+			create(desired, "replacing the destroyed instance")...)
+	}
+
+	var changes []Change
+	state := currentState(live.Status)
+	resize := !strings.EqualFold(live.Memory, desired.Memory)
+	if resize && state == StatePaused {
+		changes = append(changes, change(ActionResume, "the instance must be running to be resized"))
+		state = StateRunning
+	}
+	if resize {
+		changes = append(changes, change(ActionResize,
+			fmt.Sprintf("changing the memory from %s to %s", live.Memory, desired.Memory)))
+	}
+	switch {
+	case state == StateRunning && desired.State == StatePaused:
+		changes = append(changes, change(ActionPause, "the spec says the instance should be paused"))
+	case state == StatePaused && desired.State == StateRunning:
+		changes = append(changes, change(ActionResume, "the spec says the instance should be running"))
+	}
+	return changes
+}
+
+// create returns the changes creating the instance.
+func create(desired InstanceSpec, reason string) []Change {
+	changes := []Change{{
+		Action:   ActionCreate,
+		Name:     desired.Name,
+		TenantID: desired.TenantID,
+		Desired:  desired,
+		Reason:   reason,
+	}}
+	if desired.State == StatePaused {
+		changes = append(changes, Change{
+			Action:   ActionPause,
+			Name:     desired.Name,
+			TenantID: desired.TenantID,
+			Desired:  desired,
+			Reason:   "the spec says the instance should be paused",
+		})
+	}
+	for i := range changes {
+		changes[i].describe()
+	}
+	return changes
+}
+
+// immutableDifference returns the first field which cannot be changed without
+// replacing the instance, along with its current and desired values.
+func immutableDifference(desired InstanceSpec, live *aura.GetResponseData) (field, current, wanted string) {
+	for _, f := range [][3]string{
+		{"cloud provider", live.CloudProvider, desired.CloudProvider},
+		{"region", live.Region, desired.Region},
+		{"type", live.InstanceType, desired.InstanceType},
+	} {
+		if !strings.EqualFold(f[1], f[2]) {
+			return f[0], f[1], f[2]
+		}
+	}
+	return "", "", ""
+}
+
+// currentState returns the state an instance with the given status is in or
+// on its way to.
+func currentState(status string) string {
+	switch status {
+	case aura.StatusPaused, aura.StatusPausing:
+		return StatePaused
+	default:
+		return StateRunning
+	}
+}
+
+// Apply makes the changes of the plan in order, waiting for each instance to
+// reach the status needed for its next change. Destroying instances is refused
+// unless AllowDestroy is given. Before making any change, the instances are
+// compared with how they were when planning, returning a *DriftError if they
+// have changed since.
+func Apply(ctx context.Context, client aura.Client, plan *Plan, options ...Option) error {
+	conf := newConfig(options)
+	if destructive := plan.Destructive(); len(destructive) > 0 && !conf.allowDestroy {
+		return &DestructiveChangeError{Changes: destructive}
+	}
+	if err := checkDrift(ctx, client, plan); err != nil {
+		return err
+	}
+	type key struct{ tenant, name string }
+	created := make(map[key]string)
+	for i := range plan.Changes {
+		c := &plan.Changes[i]
+		if c.InstanceID == "" && c.Action != ActionCreate {
+			c.InstanceID = created[key{c.TenantID, c.Name}]
+		}
+		if err := apply(ctx, client, c, conf); err != nil {
+			return fmt.Errorf("failed to %s instance %s: %w", c.Action, c.Name, err)
+		}
+		if c.Action == ActionCreate {
+			created[key{c.TenantID, c.Name}] = c.InstanceID
+		}
+	}
+	return nil
+}
+
+// apply makes a single change, first waiting for the instance to have the
+// status the change needs, and then for the status the change results in.
+func apply(ctx context.Context, client aura.Client, c *Change, conf config) error {
+	wait := func(status string) error {
+		_, err := client.WaitForStatus(ctx, c.InstanceID, []string{status},
+			append([]aura.WaitOption{aura.WithFailureStatuses(aura.StatusDestroying)}, conf.waitOptions...)...)
+		return err
+	}
+	switch c.Action {
+	case ActionCreate:
+		resp, err := client.CreateInstanceFromRequestWithContext(ctx, aura.CreateInstanceRequest{
+			Name:          c.Desired.Name,
+			TenantID:      c.Desired.TenantID,
+			CloudProvider: aura.CloudProvider(c.Desired.CloudProvider),
+			Region:        c.Desired.Region,
+			InstanceType:  aura.InstanceType(c.Desired.InstanceType),
+			Memory:        c.Desired.Memory,
+			Version:       c.Desired.Version,
+		})
+		if err != nil {
+			return err
+		}
+		c.InstanceID = resp.Data.ID
+		return wait(aura.StatusRunning)
+	case ActionResize:
+		if err := wait(aura.StatusRunning); err != nil {
+			return err
+		}
+		if _, err := client.UpdateInstanceWithContext(ctx, c.InstanceID,
+			aura.UpdateRequest{Memory: c.Desired.Memory}); err != nil {
+			return err
+		}
+		return wait(aura.StatusRunning)
+	case ActionPause:
+		if err := wait(aura.StatusRunning); err != nil {
+			return err
+		}
+		if err := client.PauseInstanceWithContext(ctx, c.InstanceID); err != nil {
+			return err
+		}
+		return wait(aura.StatusPaused)
+	case ActionResume:
+		if err := wait(aura.StatusPaused); err != nil {
+			return err
+		}
+		if err := client.ResumeInstanceWithContext(ctx, c.InstanceID); err != nil {
+			return err
+		}
+		return wait(aura.StatusRunning)
+	case ActionDestroy:
+		return client.DestroyInstanceWithContext(ctx, c.InstanceID)
+	}
+	return fmt.Errorf("unknown action %q", c.Action)
+}
+
+func sortedNames(live map[string]*aura.GetResponseData) []string {
+	names := make([]string, 0, len(live))
+	for name := range live {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,233 @@
+package reconcile_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/indykite/aura-api-client/aura"
+	"github.com/indykite/aura-api-client/aura/auratest"
+	"github.com/indykite/aura-api-client/aura/reconcile"
+)
+
+const specYAML = `
+tenant: auratest-tenant
+instances:
+  - name: Production
+    cloud_provider: gcp
+    region: europe-west1
+    type: enterprise-db
+    memory: 8GB
+    version: "5"
+  - name: Staging
+    cloud_provider: gcp
+    region: europe-west1
+    type: professional-db
+    memory: 2GB
+    version: "5"
+    state: paused
+`
+
+func actions(plan *reconcile.Plan) []string {
+	var actions []string
+	for _, c := range plan.Changes {
+		actions = append(actions, string(c.Action)+" "+c.Name)
+	}
+	return actions
+}
+
+var _ = Describe("Specs", func() {
+	It("should fill in defaults", func() {
+		spec, err := reconcile.ParseSpec(strings.NewReader(specYAML))
+		Expect(err).To(Succeed())
+		Expect(spec.Instances).To(HaveLen(2))
+		Expect(spec.Instances[0].TenantID).To(Equal("auratest-tenant"))
+		Expect(spec.Instances[0].State).To(Equal(reconcile.StateRunning))
+	})
+	DescribeTable("should be rejected when invalid",
+		func(yaml, message string) {
+			_, err := reconcile.ParseSpec(strings.NewReader(yaml))
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("unknown field", "tenant: t\nsize: 3\n", "field size not found"),
+		Entry("missing tenant", "instances:\n  - name: a\n", "has no tenant"),
+		Entry("missing field", "tenant: t\ninstances:\n  - name: a\n    cloud_provider: gcp\n", "has no region"),
+		Entry("unknown state", "tenant: t\ninstances:\n  - name: a\n    state: sleeping\n", "unknown state"),
+		Entry("duplicate", "tenant: t\ninstances:\n  - name: a\n    state: absent\n  - name: a\n    state: absent\n",
+			"more than once"),
+	)
+})
+
+var _ = Describe("Reconcile", func() {
+	var (
+		server *auratest.Server
+		client aura.Client
+		spec   *reconcile.Spec
+		ctx    context.Context
+		fast   reconcile.Option
+	)
+	BeforeEach(func() {
+		ctx = context.Background()
+		server = auratest.NewServer()
+		DeferCleanup(server.Close)
+		var err error
+		client, err = server.Client(ctx)
+		Expect(err).To(Succeed())
+		spec, err = reconcile.ParseSpec(strings.NewReader(specYAML))
+		Expect(err).To(Succeed())
+		fast = reconcile.WithWaitOptions(aura.WithPollInterval(time.Millisecond, time.Millisecond))
+	})
+	It("should create missing instances", func() {
+		plan, err := reconcile.Reconcile(ctx, client, spec, fast)
+		Expect(err).To(Succeed())
+		Expect(actions(plan)).To(Equal([]string{"create Production", "create Staging", "pause Staging"}))
+		Expect(plan.Changes[2].InstanceID).NotTo(BeEmpty())
+		staging, _ := server.Instance(plan.Changes[2].InstanceID)
+		Expect(staging.Status).To(Equal(aura.StatusPaused))
+
+		plan, err = reconcile.Reconcile(ctx, client, spec, fast)
+		Expect(err).To(Succeed())
+		Expect(plan.Changes).To(BeEmpty())
+		Expect(plan.String()).To(ContainSubstring("No changes"))
+	})
+	It("should only plan changes in dry runs", func() {
+		plan, err := reconcile.Reconcile(ctx, client, spec, reconcile.DryRun())
+		Expect(err).To(Succeed())
+		Expect(plan.Changes).To(HaveLen(3))
+		Expect(plan.String()).To(ContainSubstring("+ create Production (new) in tenant auratest-tenant [billable]"))
+		Expect(plan.String()).To(ContainSubstring("Plan: 2 to create, 1 to pause."))
+		list, err := client.ListInstances("")
+		Expect(err).To(Succeed())
+		Expect(list.Data).To(BeEmpty())
+	})
+	It("should resize paused instances", func() {
+		id := server.AddInstance(aura.GetResponseData{
+			ResponseCommonProperties: aura.ResponseCommonProperties{
+				Name: "Staging", CloudProvider: "gcp", Region: "europe-west1", InstanceType: "professional-db",
+			},
+			Status: aura.StatusPaused,
+			Memory: "4GB",
+		})
+		spec.Instances = spec.Instances[1:]
+		plan, err := reconcile.Reconcile(ctx, client, spec, fast)
+		Expect(err).To(Succeed())
+		Expect(actions(plan)).To(Equal([]string{"resume Staging", "resize Staging", "pause Staging"}))
+		staging, _ := server.Instance(id)
+		Expect(staging.Memory).To(Equal("2GB"))
+		Expect(staging.Status).To(Equal(aura.StatusPaused))
+	})
+	It("should refuse to destroy instances unless allowed", func() {
+		server.AddInstance(aura.GetResponseData{
+			ResponseCommonProperties: aura.ResponseCommonProperties{
+				Name: "Production", CloudProvider: "aws", Region: "europe-west1", InstanceType: "enterprise-db",
+			},
+			Memory: "8GB",
+		})
+		server.AddInstance(aura.GetResponseData{ResponseCommonProperties: aura.ResponseCommonProperties{Name: "Old"}})
+		spec.Instances = spec.Instances[:1]
+		spec.Prune = true
+		plan, err := reconcile.Reconcile(ctx, client, spec, fast)
+		var destructive *reconcile.DestructiveChangeError
+		Expect(errors.As(err, &destructive)).To(BeTrue())
+		Expect(destructive.Changes).To(HaveLen(2))
+		Expect(actions(plan)).To(Equal([]string{"destroy Production", "create Production", "destroy Old"}))
+		Expect(plan.String()).To(ContainSubstring("change the cloud provider from aws to gcp"))
+		Expect(server.Requests()).NotTo(ContainElement(HaveField("Method", "DELETE")))
+
+		_, err = reconcile.Reconcile(ctx, client, spec, fast, reconcile.AllowDestroy())
+		Expect(err).To(Succeed())
+		list, err := client.ListInstances("")
+		Expect(err).To(Succeed())
+		Expect(list.Data).To(HaveLen(1))
+		Expect(list.Data[0].CloudProvider).To(Equal("gcp"))
+	})
+})
+
+var _ = Describe("Plans", func() {
+	var (
+		server *auratest.Server
+		client aura.Client
+		spec   *reconcile.Spec
+		ctx    context.Context
+		fast   reconcile.Option
+		id     string
+	)
+	BeforeEach(func() {
+		ctx = context.Background()
+		server = auratest.NewServer()
+		DeferCleanup(server.Close)
+		var err error
+		client, err = server.Client(ctx)
+		Expect(err).To(Succeed())
+		spec, err = reconcile.ParseSpec(strings.NewReader(specYAML))
+		Expect(err).To(Succeed())
+		fast = reconcile.WithWaitOptions(aura.WithPollInterval(time.Millisecond, time.Millisecond))
+		id = server.AddInstance(aura.GetResponseData{
+			ResponseCommonProperties: aura.ResponseCommonProperties{
+				Name: "Production", CloudProvider: "gcp", Region: "europe-west1", InstanceType: "enterprise-db",
+			},
+			Memory: "4GB",
+		})
+	})
+	It("should describe the changed fields", func() {
+		plan, err := reconcile.NewPlan(ctx, client, spec)
+		Expect(err).To(Succeed())
+		Expect(actions(plan)).To(Equal([]string{"resize Production", "create Staging", "pause Staging"}))
+		resize := plan.Changes[0]
+		Expect(resize.Current).To(HaveField("ID", id))
+		Expect(resize.Current).To(HaveField("Memory", "4GB"))
+		Expect(resize.Fields).To(Equal([]reconcile.FieldChange{{Field: "memory", Current: "4GB", Desired: "8GB"}}))
+		Expect(resize.Billable).To(BeTrue())
+		Expect(resize.Destructive).To(BeFalse())
+		Expect(plan.Changes[2].Billable).To(BeFalse())
+		Expect(plan.String()).To(ContainSubstring("~ resize Production (" + id + ") in tenant auratest-tenant [billable]"))
+		Expect(plan.String()).To(ContainSubstring("    memory: 4GB -> 8GB\n"))
+	})
+	It("should mark destroyed instances as destructive", func() {
+		spec.Instances[0].State = reconcile.StateAbsent
+		plan, err := reconcile.NewPlan(ctx, client, spec)
+		Expect(err).To(Succeed())
+		Expect(plan.Destructive()).To(HaveLen(1))
+		Expect(plan.Changes[0].Billable).To(BeFalse())
+		Expect(plan.String()).To(
+			ContainSubstring("- destroy Production (" + id + ") in tenant auratest-tenant [destructive]"))
+		Expect(plan.String()).To(ContainSubstring("    memory: 4GB -> (none)\n"))
+	})
+	It("should apply a plan read from JSON", func() {
+		plan, err := reconcile.NewPlan(ctx, client, spec)
+		Expect(err).To(Succeed())
+		var b bytes.Buffer
+		Expect(plan.WriteJSON(&b)).To(Succeed())
+		Expect(b.String()).To(ContainSubstring(`"action": "resize"`))
+		read, err := reconcile.ReadPlan(&b)
+		Expect(err).To(Succeed())
+		Expect(read).To(Equal(plan))
+
+		Expect(reconcile.Apply(ctx, client, read, fast)).To(Succeed())
+		production, _ := server.Instance(id)
+		Expect(production.Memory).To(Equal("8GB"))
+	})
+	It("should refuse to apply plans when the instances changed", func() {
+		plan, err := reconcile.NewPlan(ctx, client, spec)
+		Expect(err).To(Succeed())
+		_, err = client.UpdateInstance(id, aura.UpdateRequest{Memory: "16GB"})
+		Expect(err).To(Succeed())
+		server.AddInstance(aura.GetResponseData{ResponseCommonProperties: aura.ResponseCommonProperties{Name: "Staging"}})
+
+		err = reconcile.Apply(ctx, client, plan, fast)
+		var drift *reconcile.DriftError
+		Expect(errors.As(err, &drift)).To(BeTrue())
+		Expect(drift.Drifts).To(ConsistOf(
+			HaveField("Field", "memory"),
+			HaveField("Name", "Staging"),
+		))
+		Expect(drift.Drifts[0]).To(HaveField("Live", "16GB"))
+		Expect(err).To(MatchError(ContainSubstring(`Production memory was "4GB" when planning but is now "16GB"`)))
+		Expect(server.Requests()).NotTo(ContainElement(auratest.Request{Method: "POST", Path: "/v1/instances"}))
+	})
+})
@@ -0,0 +1,129 @@
+// Package reconcile manages Aura instances declaratively. A Spec describes the
+// desired instances, from which Reconcile plans and applies the changes needed
+// to make the live instances match.
+package reconcile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Desired states of an instance.
+const (
+	StateRunning = "running"
+	StatePaused  = "paused"
+	StateAbsent  = "absent" // The instance is destroyed if it exists
+)
+
+// Spec describes the desired instances of one or more tenants.
+type Spec struct {
+	TenantID  string         `yaml:"tenant"`    // Tenant of the instances which do not give their own
+	Prune     bool           `yaml:"prune"`     // Destroy instances of the tenants which are not in the spec
+	Instances []InstanceSpec `yaml:"instances"` // Instances, identified by their name within their tenant
+}
+
+// InstanceSpec describes a desired instance. Only the memory and state of an
+// existing instance can be changed; changing its cloud provider, region or
+// type replaces it. The version is only used when creating the instance, and
+// the state is running by default.
+type InstanceSpec struct {
+	Name          string `yaml:"name" json:"name"`
+	TenantID      string `yaml:"tenant" json:"tenant,omitempty"`
+	CloudProvider string `yaml:"cloud_provider" json:"cloud_provider,omitempty"` // gcp, aws or azure
+	Region        string `yaml:"region" json:"region,omitempty"`                 // us-east1, eu-central2, ...
+	InstanceType  string `yaml:"type" json:"type,omitempty"`                     // enterprise-db, professional-db, ...
+	Memory        string `yaml:"memory" json:"memory,omitempty"`                 // Amount of memory, i.e. "8GB"
+	Version       string `yaml:"version" json:"version,omitempty"`               // Neo4j version, i.e. "5"
+	State         string `yaml:"state" json:"state,omitempty"`                   // running, paused or absent
+}
+
+// ParseSpec parses a spec in YAML, rejecting unknown fields.
+func ParseSpec(r io.Reader) (*Spec, error) {
+	var spec Spec
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	if err := dec.Decode(&spec); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if err := spec.normalize(); err != nil {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+// LoadSpec reads a spec in YAML from the given file.
+func LoadSpec(path string) (*Spec, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	spec, err := ParseSpec(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return spec, nil
+}
+
+// normalize fills in the defaults of the instances and checks that they are complete.
+func (s *Spec) normalize() error {
+	type key struct{ tenant, name string }
+	seen := make(map[key]bool)
+	for i := range s.Instances {
+		instance := &s.Instances[i]
+		if instance.TenantID == "" {
+			instance.TenantID = s.TenantID
+		}
+		if instance.State == "" {
+			instance.State = StateRunning
+		}
+		if instance.Name == "" {
+			return fmt.Errorf("instance %d has no name", i+1)
+		}
+		if instance.TenantID == "" {
+			return fmt.Errorf("instance %s has no tenant", instance.Name)
+		}
+		if !slices.Contains([]string{StateRunning, StatePaused, StateAbsent}, instance.State) {
+			return fmt.Errorf("instance %s has unknown state %q", instance.Name, instance.State)
+		}
+		k := key{instance.TenantID, instance.Name}
+		if seen[k] {
+			return fmt.Errorf("instance %s is in tenant %s more than once", instance.Name, instance.TenantID)
+		}
+		seen[k] = true
+		if instance.State == StateAbsent {
+			continue
+		}
+		for _, f := range [][2]string{
+			{"cloud_provider", instance.CloudProvider},
+			{"region", instance.Region},
+			{"type", instance.InstanceType},
+			{"memory", instance.Memory},
+			{"version", instance.Version},
+		} {
+			if f[1] == "" {
+				return fmt.Errorf("instance %s has no %s", instance.Name, f[0])
+			}
+		}
+	}
+	return nil
+}
+
+// tenants returns the tenants of the instances in the order they first appear.
+func (s *Spec) tenants() []string {
+	var tenants []string
+	for _, i := range s.Instances {
+		if !slices.Contains(tenants, i.TenantID) {
+			tenants = append(tenants, i.TenantID)
+		}
+	}
+	if s.TenantID != "" && !slices.Contains(tenants, s.TenantID) {
+		tenants = append(tenants, s.TenantID)
+	}
+	return tenants
+}
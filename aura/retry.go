@@ -0,0 +1,193 @@
+package aura
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+const (
+	defaultRetryMinWait = time.Second
+	defaultRetryMaxWait = 30 * time.Second
+	defaultRetryJitter  = 0.1
+)
+
+// RetryPolicy decides which failed requests are retried and how long to wait
+// before retrying them. Requests throttled by Aura are not covered by the
+// policy, see WithRateLimitRetries.
+type RetryPolicy interface {
+	// MaxRetries returns how many times a request is retried at most.
+	MaxRetries() int
+	// ShouldRetry reports whether the request should be retried after either
+	// receiving an error response or failing with err, in which case resp is nil.
+	// It is not asked about successful responses.
+	ShouldRetry(req *http.Request, resp *http.Response, err error) bool
+	// Backoff returns how long to wait before the given retry, counting from 1.
+	// resp is the response of the previous attempt and may be nil.
+	Backoff(retry int, resp *http.Response) time.Duration
+}
+
+// StandardRetryPolicy is a RetryPolicy retrying requests with the given statuses,
+// waiting exponentially longer between each retry.
+type StandardRetryPolicy struct {
+	Retries     int           // Maximum number of retries
+	Statuses    []int         // Response statuses which are retried
+	RetryErrors bool          // Retry requests failing without a response, i.e. on connection errors
+	Methods     []string      // Methods which are retried, all methods are retried if empty
+	RetryCreate bool          // Retry requests creating a resource, such as an instance, which may create it twice
+	MinWait     time.Duration // Wait before the first retry
+	MaxWait     time.Duration // Maximum wait between retries
+	Jitter      float64       // Fraction by which the wait is randomly changed, i.e. 0.1 for 10%
+}
+
+// DefaultRetryPolicy retries requests on connection errors as well as the 500, 502,
+// 503 and 504 statuses as recommended by Neo4J, waiting from 1 up to 30 seconds
+// between retries. This is the policy used by WithRetries. Requests creating a
+// resource, such as an instance, are not retried since a request failing with a
+// server error may still have been carried out by Aura.
+func DefaultRetryPolicy(retries int) *StandardRetryPolicy {
+	return &StandardRetryPolicy{
+		Retries: retries,
+		Statuses: []int{
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+		RetryErrors: true,
+		MinWait:     defaultRetryMinWait,
+		MaxWait:     defaultRetryMaxWait,
+		Jitter:      defaultRetryJitter,
+	}
+}
+
+// IdempotentRetryPolicy is like DefaultRetryPolicy but only retries requests that
+// use a method which is safe to send more than once, leaving out actions such as
+// pausing an instance as well.
+func IdempotentRetryPolicy(retries int) *StandardRetryPolicy {
+	p := DefaultRetryPolicy(retries)
+	p.Methods = []string{http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions}
+	return p
+}
+
+// NoRetryPolicy never retries requests.
+func NoRetryPolicy() *StandardRetryPolicy {
+	return &StandardRetryPolicy{}
+}
+
+func (p *StandardRetryPolicy) MaxRetries() int {
+	return p.Retries
+}
+
+func (p *StandardRetryPolicy) ShouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if len(p.Methods) > 0 && (req == nil || !slices.Contains(p.Methods, req.Method)) {
+		return false
+	}
+	if !p.RetryCreate && createsResource(req) {
+		return false
+	}
+	if err != nil {
+		if !p.RetryErrors {
+			return false
+		}
+		// Leave out errors which will not go away by retrying, i.e. invalid certificates
+		retry, _ := retryablehttp.DefaultRetryPolicy(context.Background(), nil, err)
+		return retry
+	}
+	return slices.Contains(p.Statuses, resp.StatusCode)
+}
+
+// createCollections are the paths POSTed to when creating a resource.
+var createCollections = []string{"/instances", "/snapshots", "/customer-managed-keys", "/graph-analytics/sessions"}
+
+// createsResource reports whether the request creates a resource, i.e. an
+// instance or a snapshot, rather than acting on an existing one.
+func createsResource(req *http.Request) bool {
+	if req == nil || req.Method != http.MethodPost {
+		return false
+	}
+	path := strings.TrimSuffix(req.URL.Path, "/")
+	for _, collection := range createCollections {
+		if strings.HasSuffix(path, collection) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *StandardRetryPolicy) Backoff(retry int, resp *http.Response) time.Duration {
+	// Honour the wait requested by Aura when it is unavailable, up to the maximum wait
+	if resp != nil && resp.StatusCode == http.StatusServiceUnavailable && resp.Header.Get("Retry-After") != "" {
+		return min(retryAfter(resp, retry), p.MaxWait)
+	}
+	wait := p.MaxWait
+	if retry < 32 {
+		wait = min(p.MinWait<<(retry-1), p.MaxWait)
+	}
+	//nolint:gosec // No need for cryptographically secure randomness
+	return time.Duration(float64(wait) * (1 + p.Jitter*(2*rand.Float64()-1)))
+}
+
+// WithRetryPolicy sets the policy deciding which failed requests are retried
+// and how long to wait in between, replacing WithRetries.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *client) {
+		c.retryPolicy = p
+	}
+}
+
+type requestKey struct{}
+
+// withRequest stores the request in its own context, so it is available to the
+// retry policy also when no response was received.
+func withRequest(req *http.Request) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), requestKey{}, req))
+}
+
+// noRetryKey marks a context whose requests are not retried by the retry policy.
+type noRetryKey struct{}
+
+// newRetryClient returns the client sending requests to Aura, retrying them
+// according to the retry policy of c.
+func (c *client) newRetryClient() *retryablehttp.Client {
+	policy := c.retryPolicy
+	if policy == nil {
+		policy = DefaultRetryPolicy(c.retries)
+	}
+	r := retryablehttp.NewClient()
+	r.Logger = c.logger
+	r.RetryMax = policy.MaxRetries()
+	r.CheckRetry = func(ctx context.Context, resp *http.Response, err error) (bool, error) {
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+		if noRetry, _ := ctx.Value(noRetryKey{}).(bool); noRetry {
+			return false, nil
+		}
+		// Throttled requests are retried separately, honouring Retry-After
+		if err == nil && (resp.StatusCode < http.StatusBadRequest || resp.StatusCode == http.StatusTooManyRequests) {
+			return false, nil
+		}
+		req, _ := ctx.Value(requestKey{}).(*http.Request)
+		if resp != nil {
+			req = resp.Request
+		}
+		return policy.ShouldRetry(req, resp, err), nil
+	}
+	r.Backoff = func(_, _ time.Duration, attemptNum int, resp *http.Response) time.Duration {
+		return policy.Backoff(attemptNum+1, resp)
+	}
+	r.RequestLogHook = func(_ retryablehttp.Logger, req *http.Request, attemptNum int) {
+		if attemptNum > 0 {
+			recordRetry(req.Context())
+			c.logger.Info("Retrying request to the Neo4J Aura API",
+				"method", req.Method, "url", req.URL.String(), "attempt", attemptNum)
+		}
+	}
+	return r
+}
@@ -0,0 +1,313 @@
+package aura
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+)
+
+// SessionStatus is the status of a Graph Analytics session, which must be
+// ready before the Graph Data Science library can connect to it.
+type SessionStatus string
+
+// Statuses reported by the Aura API for a Graph Analytics session.
+const (
+	SessionStatusCreating SessionStatus = "Creating"
+	SessionStatusReady    SessionStatus = "Ready"
+	SessionStatusFailed   SessionStatus = "Failed"
+	SessionStatusExpired  SessionStatus = "Expired"
+)
+
+// AlgorithmCategory is a category of Graph Data Science algorithms, used to
+// estimate the memory a session needs.
+type AlgorithmCategory string
+
+const (
+	AlgorithmCategoryCentrality         AlgorithmCategory = "centrality"
+	AlgorithmCategoryCommunityDetection AlgorithmCategory = "community-detection"
+	AlgorithmCategoryMachineLearning    AlgorithmCategory = "machine-learning"
+	AlgorithmCategoryNodeEmbedding      AlgorithmCategory = "node-embedding"
+	AlgorithmCategoryPathFinding        AlgorithmCategory = "path-finding"
+	AlgorithmCategorySimilarity         AlgorithmCategory = "similarity"
+)
+
+// SessionSizeRequest describes the graph a session is going to project, and
+// is constructed from
+// https://neo4j.com/docs/aura/platform/api/specification/#/graph-analytics/post-graph-analytics-sessions-sizing.
+type SessionSizeRequest struct {
+	NodeCount           int64               `json:"node_count"`
+	RelationshipCount   int64               `json:"relationship_count"`
+	AlgorithmCategories []AlgorithmCategory `json:"algorithm_categories"`
+}
+
+type SessionSize struct {
+	EstimatedMemory  string `json:"estimated_memory"`   // Memory needed for the graph and algorithms, i.e. 3GB
+	RecommendedSize  string `json:"recommended_size"`   // Smallest session memory fitting the estimate, i.e. 4GB
+	DidExceedMaximum bool   `json:"did_exceed_maximum"` // Whether the estimate is larger than the largest session
+}
+
+// SessionSizeResponse is returned when estimating the memory of a session.
+type SessionSizeResponse struct {
+	Data SessionSize `json:"data"`
+}
+
+type SessionSummary struct {
+	ID         string `json:"id"`          // Internal ID of the session
+	Name       string `json:"name"`        // The name we chose for the session
+	TenantID   string `json:"tenant_id"`   // Tenant the session belongs to
+	InstanceID string `json:"instance_id"` // Instance the session reads from and writes to
+}
+
+// ListSessionsResponse is returned when listing Graph Analytics sessions and is
+// constructed from the values from
+// https://neo4j.com/docs/aura/platform/api/specification/#/graph-analytics/get-graph-analytics-sessions.
+type ListSessionsResponse struct {
+	Data []SessionSummary `json:"data"`
+}
+
+type Session struct {
+	SessionSummary
+	Status     SessionStatus `json:"status"`      // Creating, Ready, Failed or Expired
+	Memory     string        `json:"memory"`      // Amount of memory, i.e. 8GB
+	Host       string        `json:"host"`        // Host the Graph Data Science client connects to once ready
+	TTL        string        `json:"ttl"`         // How long the session may be idle before it is deleted, i.e. 1h
+	CreatedAt  string        `json:"created_at"`  // RFC 3339 time the session was created
+	ExpiryDate string        `json:"expiry_date"` // RFC 3339 time the session is deleted unless used
+}
+
+// SessionResponse contains information about a given Graph Analytics session
+// and is constructed from specification at
+// https://neo4j.com/docs/aura/platform/api/specification/#/graph-analytics/get-graph-analytics-sessions-id.
+type SessionResponse struct {
+	Data Session `json:"data"`
+}
+
+// CreateSessionRequest holds the parameters for creating a Graph Analytics
+// session attached to an instance.
+type CreateSessionRequest struct {
+	Name       string `json:"name"`          // The name of the session
+	TenantID   string `json:"tenant_id"`     // Defaults to the tenant of the client
+	InstanceID string `json:"instance_id"`   // Instance to attach the session to
+	Memory     string `json:"memory"`        // Amount of memory, i.e. a RecommendedSize from EstimateSessionSize
+	TTL        string `json:"ttl,omitempty"` // Idle time before the session is deleted, i.e. 30m, 1h by default
+}
+
+// EstimateSessionSize returns the memory a Graph Analytics session needs for
+// a graph of the given size and the algorithms to run on it.
+// To specify the context, use EstimateSessionSizeWithContext.
+func (c *client) EstimateSessionSize(r SessionSizeRequest) (*SessionSizeResponse, error) {
+	return c.EstimateSessionSizeWithContext(context.Background(), r)
+}
+
+// EstimateSessionSizeWithContext is like EstimateSessionSize but uses the given context for the request.
+func (c *client) EstimateSessionSizeWithContext(
+	ctx context.Context, r SessionSizeRequest,
+) (_ *SessionSizeResponse, err error) {
+	ctx, op := c.startOperation(ctx, "EstimateSessionSize")
+	defer op.end(&err)
+	req, err := c.newRequest(ctx, "POST", c.api()+"/graph-analytics/sessions/sizing", r)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, newAuraError(errors.New(resp.Status), resp)
+	}
+
+	var sizeResp SessionSizeResponse
+	err = json.NewDecoder(resp.Body).Decode(&sizeResp)
+	if err != nil {
+		return nil, newAuraError(err, resp)
+	}
+
+	return &sizeResp, nil
+}
+
+// ListSessions returns the Graph Analytics sessions the client has access to.
+// If a tenant ID or instance ID is given only sessions belonging to that tenant
+// or attached to that instance are returned.
+// To specify the context, use ListSessionsWithContext.
+func (c *client) ListSessions(tenantID, instanceID string) (*ListSessionsResponse, error) {
+	return c.ListSessionsWithContext(context.Background(), tenantID, instanceID)
+}
+
+// ListSessionsWithContext is like ListSessions but uses the given context for the request.
+func (c *client) ListSessionsWithContext(
+	ctx context.Context, tenantID, instanceID string,
+) (_ *ListSessionsResponse, err error) {
+	ctx, op := c.startOperation(ctx, "ListSessions",
+		AttributeTenantID.String(tenantID), AttributeInstanceID.String(instanceID))
+	defer op.end(&err)
+	path := c.api() + "/graph-analytics/sessions"
+	query := url.Values{}
+	if tenantID != "" {
+		query.Set("tenantId", tenantID)
+	}
+	if instanceID != "" {
+		query.Set("instanceId", instanceID)
+	}
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	req, err := c.newRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, newAuraError(errors.New(resp.Status), resp)
+	}
+
+	var listResp ListSessionsResponse
+	err = json.NewDecoder(resp.Body).Decode(&listResp)
+	if err != nil {
+		return nil, newAuraError(err, resp)
+	}
+
+	return &listResp, nil
+}
+
+// CreateSession creates a Graph Analytics session attached to an instance.
+// The returned session is "Creating" until it can be connected to, see
+// WaitForSession. Aura deletes the session once it has been idle for its TTL.
+// To specify the context, use CreateSessionWithContext.
+func (c *client) CreateSession(r CreateSessionRequest) (*SessionResponse, error) {
+	return c.CreateSessionWithContext(context.Background(), r)
+}
+
+// CreateSessionWithContext is like CreateSession but uses the given context for the request.
+func (c *client) CreateSessionWithContext(ctx context.Context, r CreateSessionRequest) (_ *SessionResponse, err error) {
+	if r.TenantID == "" {
+		r.TenantID = c.tenantID
+	}
+	ctx, op := c.startOperation(ctx, "CreateSession",
+		AttributeTenantID.String(r.TenantID), AttributeInstanceID.String(r.InstanceID))
+	defer op.end(&err)
+	req, err := c.newRequest(ctx, "POST", c.api()+"/graph-analytics/sessions", r)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, newAuraError(errors.New(resp.Status), resp)
+	}
+
+	var createResp SessionResponse
+	err = json.NewDecoder(resp.Body).Decode(&createResp)
+	if err != nil {
+		return nil, newAuraError(err, resp)
+	}
+
+	op.span.SetAttributes(AttributeSessionID.String(createResp.Data.ID))
+	return &createResp, nil
+}
+
+// GetSession returns the Graph Analytics session with the given ID.
+// To specify the context, use GetSessionWithContext.
+func (c *client) GetSession(id string) (*SessionResponse, error) {
+	return c.GetSessionWithContext(context.Background(), id)
+}
+
+// GetSessionWithContext is like GetSession but uses the given context for the request.
+func (c *client) GetSessionWithContext(ctx context.Context, id string) (_ *SessionResponse, err error) {
+	ctx, op := c.startOperation(ctx, "GetSession", AttributeSessionID.String(id))
+	defer op.end(&err)
+	req, err := c.newRequest(ctx, "GET", c.api()+"/graph-analytics/sessions/"+id, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, newAuraError(errors.New(resp.Status), resp)
+	}
+
+	var getResp SessionResponse
+	err = json.NewDecoder(resp.Body).Decode(&getResp)
+	if err != nil {
+		return nil, newAuraError(err, resp)
+	}
+
+	return &getResp, nil
+}
+
+// DeleteSession deletes a Graph Analytics session, discarding the graphs
+// projected in it which have not been written back to the instance.
+// A 404 from the API is seen as successful as it indicates the session no longer exists.
+// To specify the context, use DeleteSessionWithContext.
+func (c *client) DeleteSession(id string) error {
+	return c.DeleteSessionWithContext(context.Background(), id)
+}
+
+// DeleteSessionWithContext is like DeleteSession but uses the given context for the request.
+func (c *client) DeleteSessionWithContext(ctx context.Context, id string) (err error) {
+	ctx, op := c.startOperation(ctx, "DeleteSession", AttributeSessionID.String(id))
+	defer op.end(&err)
+	req, err := c.newRequest(ctx, "DELETE", c.api()+"/graph-analytics/sessions/"+id, nil)
+	if err != nil {
+		return err
+	}
+	apiResp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer apiResp.Body.Close()
+	if apiResp.StatusCode == http.StatusNotFound ||
+		(apiResp.StatusCode >= http.StatusOK && apiResp.StatusCode < http.StatusMultipleChoices) {
+		return nil
+	}
+	return newAuraError(errors.New(apiResp.Status), apiResp)
+}
+
+// WaitForSession polls the session until it is ready, returning the last
+// response from Aura. A FailureStatusError is returned if the session reaches
+// the status "Failed" or "Expired", or any status given using WithFailureStatuses.
+// The context and WithWaitTimeout bound the total time spent waiting, after
+// which a WaitTimeoutError is returned.
+func (c *client) WaitForSession(ctx context.Context, id string, options ...WaitOption) (_ *SessionResponse, err error) {
+	ctx, op := c.startOperation(ctx, "WaitForSession", AttributeSessionID.String(id))
+	defer op.end(&err)
+	conf := newWaitConfig(options)
+	conf.failureStatuses = slices.Clone(conf.failureStatuses)
+	for _, s := range []SessionStatus{SessionStatusFailed, SessionStatusExpired} {
+		if !slices.Contains(conf.failureStatuses, string(s)) {
+			conf.failureStatuses = append(conf.failureStatuses, string(s))
+		}
+	}
+	var last *SessionResponse
+	targets := []string{string(SessionStatusReady)}
+	err = poll(ctx, id, targets, conf, func(ctx context.Context) (string, error) {
+		resp, err := c.GetSessionWithContext(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		last = resp
+		return string(resp.Data.Status), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return last, nil
+}
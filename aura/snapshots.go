@@ -0,0 +1,199 @@
+package aura
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+// Statuses reported by the Aura API for a snapshot.
+const (
+	SnapshotStatusPending    = "Pending"
+	SnapshotStatusInProgress = "InProgress"
+	SnapshotStatusCompleted  = "Completed"
+	SnapshotStatusFailed     = "Failed"
+)
+
+// Snapshot describes a backup of an instance and is constructed from
+// https://neo4j.com/docs/aura/platform/api/specification/#/instances/get-snapshot.
+type Snapshot struct {
+	InstanceID string `json:"instance_id"` // Instance the snapshot was taken of
+	SnapshotID string `json:"snapshot_id"` // Internal ID of the snapshot
+	Profile    string `json:"profile"`     // AddHoc or Scheduled
+	Status     string `json:"status"`      // Pending, InProgress, Completed or Failed
+	Timestamp  string `json:"timestamp"`   // RFC 3339 time the snapshot was taken
+}
+
+// ListSnapshotsResponse is returned when listing the snapshots of an instance.
+type ListSnapshotsResponse struct {
+	Data []Snapshot `json:"data"`
+}
+
+// GetSnapshotResponse contains information about a single snapshot.
+type GetSnapshotResponse struct {
+	Data Snapshot `json:"data"`
+}
+
+type CreateSnapshotResponseData struct {
+	SnapshotID string `json:"snapshot_id"` // Internal ID of the snapshot being taken
+}
+
+// CreateSnapshotResponse is returned when taking an on-demand snapshot.
+type CreateSnapshotResponse struct {
+	Data CreateSnapshotResponseData `json:"data"`
+}
+
+// ListSnapshots returns the snapshots of the given instance. If a date in the
+// format YYYY-MM-DD is given only snapshots taken on that day are returned,
+// otherwise the API defaults to the snapshots of the current day.
+// To specify the context, use ListSnapshotsWithContext.
+func (c *client) ListSnapshots(instanceID, date string) (*ListSnapshotsResponse, error) {
+	return c.ListSnapshotsWithContext(context.Background(), instanceID, date)
+}
+
+// ListSnapshotsWithContext is like ListSnapshots but uses the given context for the request.
+func (c *client) ListSnapshotsWithContext(
+	ctx context.Context, instanceID, date string,
+) (_ *ListSnapshotsResponse, err error) {
+	ctx, op := c.startOperation(ctx, "ListSnapshots", AttributeInstanceID.String(instanceID))
+	defer op.end(&err)
+	path := c.api() + "/instances/" + instanceID + "/snapshots"
+	if date != "" {
+		path += "?" + url.Values{"date": {date}}.Encode()
+	}
+	req, err := c.newRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, newAuraError(errors.New(resp.Status), resp)
+	}
+
+	var listResp ListSnapshotsResponse
+	err = json.NewDecoder(resp.Body).Decode(&listResp)
+	if err != nil {
+		return nil, newAuraError(err, resp)
+	}
+
+	return &listResp, nil
+}
+
+// CreateSnapshot takes an on-demand snapshot of the given instance. The
+// snapshot is taken asynchronously, so use GetSnapshot with the returned ID
+// to follow its progress.
+// To specify the context, use CreateSnapshotWithContext.
+func (c *client) CreateSnapshot(instanceID string) (*CreateSnapshotResponse, error) {
+	return c.CreateSnapshotWithContext(context.Background(), instanceID)
+}
+
+// CreateSnapshotWithContext is like CreateSnapshot but uses the given context for the request.
+func (c *client) CreateSnapshotWithContext(
+	ctx context.Context, instanceID string,
+) (_ *CreateSnapshotResponse, err error) {
+	ctx, op := c.startOperation(ctx, "CreateSnapshot", AttributeInstanceID.String(instanceID))
+	defer op.end(&err)
+	req, err := c.newRequest(ctx, "POST", c.api()+"/instances/"+instanceID+"/snapshots", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, newAuraError(errors.New(resp.Status), resp)
+	}
+
+	var createResp CreateSnapshotResponse
+	err = json.NewDecoder(resp.Body).Decode(&createResp)
+	if err != nil {
+		return nil, newAuraError(err, resp)
+	}
+
+	op.span.SetAttributes(AttributeSnapshotID.String(createResp.Data.SnapshotID))
+	return &createResp, nil
+}
+
+// GetSnapshot returns the status, profile and timestamp of a single snapshot.
+// To specify the context, use GetSnapshotWithContext.
+func (c *client) GetSnapshot(instanceID, snapshotID string) (*GetSnapshotResponse, error) {
+	return c.GetSnapshotWithContext(context.Background(), instanceID, snapshotID)
+}
+
+// GetSnapshotWithContext is like GetSnapshot but uses the given context for the request.
+func (c *client) GetSnapshotWithContext(
+	ctx context.Context, instanceID, snapshotID string,
+) (_ *GetSnapshotResponse, err error) {
+	ctx, op := c.startOperation(ctx, "GetSnapshot",
+		AttributeInstanceID.String(instanceID), AttributeSnapshotID.String(snapshotID))
+	defer op.end(&err)
+	req, err := c.newRequest(ctx, "GET", c.api()+"/instances/"+instanceID+"/snapshots/"+snapshotID, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, newAuraError(errors.New(resp.Status), resp)
+	}
+
+	var getResp GetSnapshotResponse
+	err = json.NewDecoder(resp.Body).Decode(&getResp)
+	if err != nil {
+		return nil, newAuraError(err, resp)
+	}
+
+	return &getResp, nil
+}
+
+// RestoreSnapshot restores an instance to the state of the given snapshot,
+// returning the instance which will be "restoring" until Aura is done.
+// Note that all data written to the instance after the snapshot was taken is lost.
+// To specify the context, use RestoreSnapshotWithContext.
+func (c *client) RestoreSnapshot(instanceID, snapshotID string) (*GetResponse, error) {
+	return c.RestoreSnapshotWithContext(context.Background(), instanceID, snapshotID)
+}
+
+// RestoreSnapshotWithContext is like RestoreSnapshot but uses the given context for the request.
+func (c *client) RestoreSnapshotWithContext(
+	ctx context.Context, instanceID, snapshotID string,
+) (_ *GetResponse, err error) {
+	ctx, op := c.startOperation(ctx, "RestoreSnapshot",
+		AttributeInstanceID.String(instanceID), AttributeSnapshotID.String(snapshotID))
+	defer op.end(&err)
+	req, err := c.newRequest(ctx, "POST", c.api()+"/instances/"+instanceID+"/snapshots/"+snapshotID+"/restore", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, newAuraError(errors.New(resp.Status), resp)
+	}
+
+	var restoreResp GetResponse
+	err = json.NewDecoder(resp.Body).Decode(&restoreResp)
+	if err != nil {
+		return nil, newAuraError(err, resp)
+	}
+
+	return &restoreResp, nil
+}
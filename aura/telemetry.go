@@ -0,0 +1,209 @@
+package aura
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/trace"
+	tracenoop "go.opentelemetry.io/otel/trace/noop"
+)
+
+// instrumentationName identifies the spans and metrics of the client.
+const instrumentationName = "github.com/indykite/aura-api-client/aura"
+
+// Attributes of the spans created for each operation.
+const (
+	AttributeInstanceID           = attribute.Key("aura.instance.id")
+	AttributeSnapshotID           = attribute.Key("aura.snapshot.id")
+	AttributeCustomerManagedKeyID = attribute.Key("aura.customer_managed_key.id")
+	AttributeSessionID            = attribute.Key("aura.session.id")
+	AttributeTenantID             = attribute.Key("aura.tenant.id")
+	AttributeRequestID            = attribute.Key("aura.request.id")
+	AttributeRetryCount           = attribute.Key("aura.retry.count")
+	AttributeStatusCode           = attribute.Key("http.response.status_code")
+	AttributeOperation            = attribute.Key("aura.operation")
+	AttributeOutcome              = attribute.Key("aura.outcome")
+)
+
+// Names of the metrics recorded by the client.
+const (
+	MetricOperationDuration = "aura.client.operation.duration"
+	MetricOperations        = "aura.client.operations"
+	MetricRetries           = "aura.client.retries"
+)
+
+// WithTracerProvider makes the client create a span for each operation, i.e.
+// "aura.CreateInstance", using the given provider. The spans have attributes
+// for the instance, the status and Aura request ID of the last response, and
+// how many times the requests were retried. By default no spans are created.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *client) {
+		c.telemetry.tracer = tp.Tracer(instrumentationName)
+	}
+}
+
+// WithMeterProvider makes the client record the duration and outcome of each
+// operation, and the number of retries, using the given provider.
+// By default no metrics are recorded.
+func WithMeterProvider(mp metric.MeterProvider) Option {
+	return func(c *client) {
+		c.telemetry.meter = mp.Meter(instrumentationName)
+	}
+}
+
+// telemetry holds the instruments used for the operations of a client.
+type telemetry struct {
+	tracer     trace.Tracer
+	meter      metric.Meter
+	duration   metric.Float64Histogram
+	operations metric.Int64Counter
+	retries    metric.Int64Counter
+}
+
+// newTelemetry returns telemetry doing nothing, until changed by the options.
+func newTelemetry() *telemetry {
+	return &telemetry{
+		tracer: tracenoop.NewTracerProvider().Tracer(instrumentationName),
+		meter:  metricnoop.NewMeterProvider().Meter(instrumentationName),
+	}
+}
+
+// init creates the instruments once the options have been applied.
+func (t *telemetry) init() error {
+	var err error
+	t.duration, err = t.meter.Float64Histogram(MetricOperationDuration,
+		metric.WithDescription("Duration of operations on the Neo4J Aura API, including retries"),
+		metric.WithUnit("s"))
+	if err != nil {
+		return err
+	}
+	t.operations, err = t.meter.Int64Counter(MetricOperations,
+		metric.WithDescription("Operations on the Neo4J Aura API by outcome"),
+		metric.WithUnit("{operation}"))
+	if err != nil {
+		return err
+	}
+	t.retries, err = t.meter.Int64Counter(MetricRetries,
+		metric.WithDescription("Requests to the Neo4J Aura API which were retried"),
+		metric.WithUnit("{retry}"))
+	return err
+}
+
+type operationKey struct{}
+
+// operation records the responses of the requests made by an operation.
+type operation struct {
+	name      string
+	start     time.Time
+	span      trace.Span
+	telemetry *telemetry
+
+	mu         sync.Mutex
+	statusCode int
+	requestID  string
+	retries    int
+}
+
+// startOperation starts a span for the named operation, which is ended by
+// calling end with the error the operation returned.
+func (c *client) startOperation(
+	ctx context.Context, name string, attrs ...attribute.KeyValue,
+) (context.Context, *operation) {
+	op := &operation{name: "aura." + name, start: time.Now(), telemetry: c.telemetry}
+	ctx, op.span = c.telemetry.tracer.Start(ctx, op.name,
+		trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	return context.WithValue(ctx, operationKey{}, op), op
+}
+
+// end ends the span of the operation and records its metrics. It takes a
+// pointer so it can be deferred with the named error of the operation.
+func (op *operation) end(err *error) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	ctx := context.Background()
+	outcome := outcomeOf(*err)
+	// Responses are not returned when giving up retrying, but kept in the error
+	var auraErr *AuraError
+	if errors.As(*err, &auraErr) && auraErr.StatusCode != 0 {
+		op.statusCode, op.requestID = auraErr.StatusCode, auraErr.RequestID
+	}
+	if op.statusCode != 0 {
+		op.span.SetAttributes(AttributeStatusCode.Int(op.statusCode))
+	}
+	if op.requestID != "" {
+		op.span.SetAttributes(AttributeRequestID.String(op.requestID))
+	}
+	op.span.SetAttributes(AttributeRetryCount.Int(op.retries))
+	if *err != nil {
+		op.span.RecordError(*err)
+		op.span.SetStatus(codes.Error, outcome)
+	}
+	op.span.End()
+
+	attrs := metric.WithAttributes(AttributeOperation.String(op.name), AttributeOutcome.String(outcome))
+	op.telemetry.duration.Record(ctx, time.Since(op.start).Seconds(), attrs)
+	op.telemetry.operations.Add(ctx, 1, attrs)
+}
+
+// recordResponse records the response to a request made by the operation of
+// the context, if any.
+func recordResponse(ctx context.Context, resp *http.Response) {
+	if op, ok := ctx.Value(operationKey{}).(*operation); ok {
+		op.mu.Lock()
+		defer op.mu.Unlock()
+		op.statusCode = resp.StatusCode
+		op.requestID = resp.Header.Get("X-Request-Id")
+	}
+}
+
+// recordRetry records that a request made by the operation of the context was retried.
+func recordRetry(ctx context.Context) {
+	op, ok := ctx.Value(operationKey{}).(*operation)
+	if !ok {
+		return
+	}
+	op.mu.Lock()
+	op.retries++
+	op.mu.Unlock()
+	op.telemetry.retries.Add(ctx, 1, metric.WithAttributes(AttributeOperation.String(op.name)))
+}
+
+// outcomeOf classifies the error of an operation for use as a metric attribute.
+func outcomeOf(err error) string {
+	var (
+		timeout *WaitTimeoutError
+		failure *FailureStatusError
+	)
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.As(err, &timeout):
+		return "wait_timeout"
+	case errors.As(err, &failure):
+		return "failure_status"
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return "canceled"
+	case errors.Is(err, ErrValidation):
+		return "validation"
+	case errors.Is(err, ErrUnauthorized):
+		return "unauthorized"
+	case errors.Is(err, ErrForbidden):
+		return "forbidden"
+	case errors.Is(err, ErrNotFound):
+		return "not_found"
+	case errors.Is(err, ErrConflict):
+		return "conflict"
+	case errors.Is(err, ErrRateLimited):
+		return "rate_limited"
+	case errors.Is(err, ErrServer):
+		return "server_error"
+	}
+	return "error"
+}
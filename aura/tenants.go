@@ -0,0 +1,159 @@
+package aura
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// InstanceConfiguration is one of the combinations of settings a tenant
+// is allowed to create instances with.
+type InstanceConfiguration struct {
+	CloudProvider string `json:"cloud_provider"` // GCP, AWS, ...
+	Region        string `json:"region"`         // us-east1, eu-central2, ...
+	RegionName    string `json:"region_name"`    // Human readable name of the region
+	Memory        string `json:"memory"`         // Amount of memory allocated, i.e. "8GB"
+	Storage       string `json:"storage"`        // Amount of storage allocated, i.e. "16GB"
+	InstanceType  string `json:"type"`           // enterprise-db, professional-db, ...
+	Version       string `json:"version"`        // Neo4j version, i.e. "5"
+}
+
+type TenantSummary struct {
+	ID   string `json:"id"`   // Internal ID of the tenant
+	Name string `json:"name"` // Name of the tenant as shown in the Aura console
+}
+
+// ListTenantsResponse is returned when listing tenants and is
+// constructed from the values from
+// https://neo4j.com/docs/aura/platform/api/specification/#/tenants/get-tenants.
+type ListTenantsResponse struct {
+	Data []TenantSummary `json:"data"`
+}
+
+type TenantResponseData struct {
+	TenantSummary
+	InstanceConfigurations []InstanceConfiguration `json:"instance_configurations"`
+}
+
+// TenantResponse contains information about a given tenant and
+// is constructed from specification at
+// https://neo4j.com/docs/aura/platform/api/specification/#/tenants/get-tenant-id.
+type TenantResponse struct {
+	Data TenantResponseData `json:"data"`
+}
+
+// ListTenants returns the tenants the client has access to.
+// To specify the context, use ListTenantsWithContext.
+func (c *client) ListTenants() (*ListTenantsResponse, error) {
+	return c.ListTenantsWithContext(context.Background())
+}
+
+// ListTenantsWithContext is like ListTenants but uses the given context for the request.
+func (c *client) ListTenantsWithContext(ctx context.Context) (_ *ListTenantsResponse, err error) {
+	ctx, op := c.startOperation(ctx, "ListTenants")
+	defer op.end(&err)
+	req, err := c.newRequest(ctx, "GET", c.api()+"/tenants", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, newAuraError(errors.New(resp.Status), resp)
+	}
+
+	var listResp ListTenantsResponse
+	err = json.NewDecoder(resp.Body).Decode(&listResp)
+	if err != nil {
+		return nil, newAuraError(err, resp)
+	}
+
+	return &listResp, nil
+}
+
+// GetTenant returns the tenant with the given ID along with the instance
+// configurations it is allowed to create.
+// To specify the context, use GetTenantWithContext.
+func (c *client) GetTenant(id string) (*TenantResponse, error) {
+	return c.GetTenantWithContext(context.Background(), id)
+}
+
+// GetTenantWithContext is like GetTenant but uses the given context for the request.
+func (c *client) GetTenantWithContext(ctx context.Context, id string) (_ *TenantResponse, err error) {
+	ctx, op := c.startOperation(ctx, "GetTenant", AttributeTenantID.String(id))
+	defer op.end(&err)
+	req, err := c.newRequest(ctx, "GET", c.api()+"/tenants/"+id, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, newAuraError(errors.New(resp.Status), resp)
+	}
+
+	var getResp TenantResponse
+	err = json.NewDecoder(resp.Body).Decode(&getResp)
+	if err != nil {
+		return nil, newAuraError(err, resp)
+	}
+
+	return &getResp, nil
+}
+
+type MetricsIntegration struct {
+	Endpoint string `json:"endpoint"` // Prometheus endpoint with the metrics of all instances of the tenant
+}
+
+// MetricsIntegrationResponse contains the endpoint to scrape the metrics of a
+// tenant from, and is constructed from specification at
+// https://neo4j.com/docs/aura/platform/api/specification/#/tenants/get-tenants-tenantId-metrics-integration.
+type MetricsIntegrationResponse struct {
+	Data MetricsIntegration `json:"data"`
+}
+
+// GetMetricsIntegration returns the Prometheus endpoint exposing the metrics
+// of the instances of the tenant, which can be scraped using ScrapeMetrics.
+// The endpoint of a single instance is the MetricsIntegrationURL of the instance.
+// To specify the context, use GetMetricsIntegrationWithContext.
+func (c *client) GetMetricsIntegration(tenantID string) (*MetricsIntegrationResponse, error) {
+	return c.GetMetricsIntegrationWithContext(context.Background(), tenantID)
+}
+
+// GetMetricsIntegrationWithContext is like GetMetricsIntegration but uses the given context for the request.
+func (c *client) GetMetricsIntegrationWithContext(
+	ctx context.Context, tenantID string,
+) (_ *MetricsIntegrationResponse, err error) {
+	ctx, op := c.startOperation(ctx, "GetMetricsIntegration", AttributeTenantID.String(tenantID))
+	defer op.end(&err)
+	req, err := c.newRequest(ctx, "GET", c.api()+"/tenants/"+tenantID+"/metrics-integration", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, newAuraError(errors.New(resp.Status), resp)
+	}
+
+	var getResp MetricsIntegrationResponse
+	err = json.NewDecoder(resp.Body).Decode(&getResp)
+	if err != nil {
+		return nil, newAuraError(err, resp)
+	}
+
+	return &getResp, nil
+}
@@ -0,0 +1,221 @@
+package aura
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+const (
+	defaultRefreshAhead = 5 * time.Minute
+	staleLockAge        = time.Minute
+	maxLockPollInterval = 200 * time.Millisecond
+)
+
+// FileTokenOption customizes a FileTokenSource.
+type FileTokenOption func(*FileTokenSource)
+
+// WithRefreshAhead sets how long before they expire tokens are replaced,
+// five minutes by default.
+func WithRefreshAhead(d time.Duration) FileTokenOption {
+	return func(s *FileTokenSource) {
+		s.refreshAhead = d
+	}
+}
+
+// WithTokenCacheFile makes the client keep its tokens in the file at path,
+// so that processes using the same credentials share a token instead of each
+// fetching their own. See NewFileTokenSource.
+func WithTokenCacheFile(path string, options ...FileTokenOption) Option {
+	return func(c *client) {
+		c.tokenCacheFile = path
+		c.tokenCacheOpts = options
+	}
+}
+
+// FileTokenSource is a TokenSource keeping the tokens of another source in a
+// file, which can be shared by several processes.
+type FileTokenSource struct {
+	path         string
+	source       TokenSource
+	refreshAhead time.Duration
+
+	mu    sync.Mutex
+	token *cachedToken
+}
+
+// NewFileTokenSource returns a source using the token stored at path while it
+// is valid, and otherwise getting a new token from source and storing it.
+// Tokens are replaced ahead of expiry, see WithRefreshAhead, but no earlier
+// than halfway through their lifetime, and are returned with their expiry
+// brought forward accordingly so callers keeping them do the same. Processes
+// getting a new token at the same time take turns using a lock file next to
+// the token, so only one of them calls source.
+//
+// The file holds a valid token for the credentials of source, so it is only
+// readable by its owner, and each set of credentials needs a file of its own.
+// Failing to read or write the file is not an error, the token is then simply
+// not shared.
+func NewFileTokenSource(path string, source TokenSource, options ...FileTokenOption) *FileTokenSource {
+	s := &FileTokenSource{
+		path:         path,
+		source:       source,
+		refreshAhead: defaultRefreshAhead,
+	}
+	for _, o := range options {
+		o(s)
+	}
+	return s
+}
+
+// cachedToken is the content of the token file.
+type cachedToken struct {
+	AccessToken string    `json:"access_token"`
+	TokenType   string    `json:"token_type"`
+	Expiry      time.Time `json:"expiry"`
+	Obtained    time.Time `json:"obtained"` // When the token was fetched, giving its lifetime
+}
+
+// Token returns the stored token if it is still valid and otherwise gets a new one.
+func (s *FileTokenSource) Token(ctx context.Context) (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fresh(s.token) {
+		return s.early(s.token), nil
+	}
+	if token := s.read(); s.fresh(token) {
+		s.token = token
+		return s.early(token), nil
+	}
+	unlock, err := s.lock(ctx)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		// The token cannot be shared, i.e. as the directory is not writable
+		return s.fetch(ctx, false)
+	}
+	defer unlock()
+	// Another process may have stored a new token while waiting for the lock
+	if token := s.read(); s.fresh(token) {
+		s.token = token
+		return s.early(token), nil
+	}
+	return s.fetch(ctx, true)
+}
+
+// fetch gets a new token from the source, storing it in the file if store is set.
+func (s *FileTokenSource) fetch(ctx context.Context, store bool) (*oauth2.Token, error) {
+	t, err := s.source.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	token := &cachedToken{AccessToken: t.AccessToken, TokenType: t.TokenType, Expiry: t.Expiry, Obtained: time.Now()}
+	s.token = token
+	if store {
+		_ = s.write(token)
+	}
+	return s.early(token), nil
+}
+
+// fresh reports whether the refresh window of the token has not started yet.
+func (s *FileTokenSource) fresh(token *cachedToken) bool {
+	if token == nil || token.AccessToken == "" {
+		return false
+	}
+	return token.Expiry.IsZero() || time.Now().Before(s.refreshAt(token))
+}
+
+// refreshAt returns when the refresh window of the token starts. The window is
+// at most half the lifetime of the token, so short-lived tokens are still shared.
+func (s *FileTokenSource) refreshAt(token *cachedToken) time.Time {
+	window := s.refreshAhead
+	if !token.Obtained.IsZero() {
+		window = min(window, token.Expiry.Sub(token.Obtained)/2)
+	}
+	return token.Expiry.Add(-window)
+}
+
+// early returns the token expiring at the start of its refresh window.
+func (s *FileTokenSource) early(token *cachedToken) *oauth2.Token {
+	t := &oauth2.Token{AccessToken: token.AccessToken, TokenType: token.TokenType}
+	if !token.Expiry.IsZero() {
+		t.Expiry = s.refreshAt(token)
+	}
+	return t
+}
+
+// read returns the token in the file, or nil if there is none.
+func (s *FileTokenSource) read() *cachedToken {
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil
+	}
+	var token cachedToken
+	if json.Unmarshal(b, &token) != nil {
+		return nil
+	}
+	return &token
+}
+
+// write replaces the file with the token. The token is written to a temporary
+// file first, so other processes never read a partially written token.
+func (s *FileTokenSource) write(token *cachedToken) error {
+	b, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), s.path)
+}
+
+// lock creates the lock file, waiting while another process holds it. Lock
+// files older than staleLockAge are left behind by processes which died while
+// getting a token, and are removed.
+func (s *FileTokenSource) lock(ctx context.Context) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return nil, err
+	}
+	path := s.path + ".lock"
+	interval := 10 * time.Millisecond
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > staleLockAge {
+			os.Remove(path)
+			continue
+		}
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		interval = min(2*interval, maxLockPollInterval)
+	}
+}
@@ -0,0 +1,119 @@
+package aura
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// maxAlternatives caps the number of alternatives listed in a ConfigurationError.
+const maxAlternatives = 5
+
+// ConfigurationError is returned when an instance is requested with a
+// combination of settings the tenant is not allowed to create. It is
+// detected locally, so no request has been sent to Aura.
+type ConfigurationError struct {
+	TenantID     string
+	Requested    InstanceConfiguration
+	Alternatives []InstanceConfiguration // The allowed configurations closest to the requested one
+}
+
+func (e *ConfigurationError) Error() string {
+	alternatives := make([]string, 0, len(e.Alternatives))
+	for _, a := range e.Alternatives {
+		alternatives = append(alternatives, describeConfiguration(a))
+	}
+	return fmt.Sprintf("instance configuration %s is not allowed for tenant %s\nNearest allowed configurations:\n  %s",
+		describeConfiguration(e.Requested), e.TenantID, strings.Join(alternatives, "\n  "))
+}
+
+// Is makes a ConfigurationError match ErrValidation, like the error Aura
+// would have returned for the same request.
+func (e *ConfigurationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// WithConfigValidation makes the client check instances against the instance
+// configurations of their tenant before asking Aura to create them. The
+// configurations of a tenant are fetched the first time they are needed and
+// then cached.
+func WithConfigValidation() Option {
+	return func(c *client) {
+		c.validateConfig = true
+	}
+}
+
+// validateConfiguration returns a ConfigurationError if the requested
+// configuration is not among the ones allowed for the tenant.
+func (c *client) validateConfiguration(ctx context.Context, tenantID string, requested InstanceConfiguration) error {
+	allowed, err := c.instanceConfigurations(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	best := 0
+	var alternatives []InstanceConfiguration
+	for _, a := range allowed {
+		score := matchingFields(requested, a)
+		switch {
+		case score == 5:
+			return nil
+		case score > best:
+			best = score
+			alternatives = []InstanceConfiguration{a}
+		case score == best && len(alternatives) < maxAlternatives:
+			alternatives = append(alternatives, a)
+		}
+	}
+	return &ConfigurationError{
+		TenantID:     tenantID,
+		Requested:    requested,
+		Alternatives: alternatives,
+	}
+}
+
+// instanceConfigurations returns the cached instance configurations of the
+// tenant, fetching them from Aura if they have not been fetched before. The
+// lock is not held while fetching, so creating instances in other tenants is
+// not held up, and callers fetching at the same time each fetch them.
+func (c *client) instanceConfigurations(ctx context.Context, tenantID string) ([]InstanceConfiguration, error) {
+	c.configMu.Lock()
+	configurations, ok := c.configurations[tenantID]
+	c.configMu.Unlock()
+	if ok {
+		return configurations, nil
+	}
+	tenant, err := c.GetTenantWithContext(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	c.configMu.Lock()
+	defer c.configMu.Unlock()
+	if c.configurations == nil {
+		c.configurations = make(map[string][]InstanceConfiguration)
+	}
+	c.configurations[tenantID] = tenant.Data.InstanceConfigurations
+	return tenant.Data.InstanceConfigurations, nil
+}
+
+// matchingFields counts the fields that can be given when creating an
+// instance which are the same in both configurations.
+func matchingFields(a, b InstanceConfiguration) int {
+	n := 0
+	for _, f := range [][2]string{
+		{a.CloudProvider, b.CloudProvider},
+		{a.Region, b.Region},
+		{a.Memory, b.Memory},
+		{a.InstanceType, b.InstanceType},
+		{a.Version, b.Version},
+	} {
+		if strings.EqualFold(f[0], f[1]) {
+			n++
+		}
+	}
+	return n
+}
+
+func describeConfiguration(conf InstanceConfiguration) string {
+	return fmt.Sprintf("cloud_provider=%s region=%s memory=%s type=%s version=%s",
+		conf.CloudProvider, conf.Region, conf.Memory, conf.InstanceType, conf.Version)
+}
@@ -0,0 +1,182 @@
+package aura
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPollInterval    = 5 * time.Second
+	defaultMaxPollInterval = time.Minute
+	pollBackoffFactor      = 1.5
+	pollJitter             = 0.2
+)
+
+// WaitTimeoutError is returned when waiting for a status took longer than
+// allowed, either by the wait timeout or the deadline of the context.
+type WaitTimeoutError struct {
+	ID         string   // ID of the resource that was waited for
+	Targets    []string // The statuses that were waited for
+	LastStatus string   // The last status observed before giving up
+	Err        error    // The context error which stopped the wait
+}
+
+func (e *WaitTimeoutError) Error() string {
+	return fmt.Sprintf("gave up waiting for %s to become %s, last status was %q: %v",
+		e.ID, strings.Join(e.Targets, " or "), e.LastStatus, e.Err)
+}
+
+func (e *WaitTimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// FailureStatusError is returned when a resource reaches a status from
+// which the status waited for can no longer be reached.
+type FailureStatusError struct {
+	ID      string   // ID of the resource that was waited for
+	Targets []string // The statuses that were waited for
+	Status  string   // The failure status that was reached
+}
+
+func (e *FailureStatusError) Error() string {
+	return fmt.Sprintf("%s reached status %q while waiting for it to become %s",
+		e.ID, e.Status, strings.Join(e.Targets, " or "))
+}
+
+// WaitOption customizes how WaitForStatus polls Aura.
+type WaitOption func(*waitConfig)
+
+type waitConfig struct {
+	interval        time.Duration
+	maxInterval     time.Duration
+	timeout         time.Duration
+	failureStatuses []string
+	progress        func(status string)
+}
+
+func newWaitConfig(options []WaitOption) waitConfig {
+	conf := waitConfig{
+		interval:    defaultPollInterval,
+		maxInterval: defaultMaxPollInterval,
+	}
+	for _, o := range options {
+		o(&conf)
+	}
+	if conf.interval <= 0 {
+		conf.interval = defaultPollInterval
+	}
+	if conf.maxInterval <= 0 {
+		conf.maxInterval = defaultMaxPollInterval
+	}
+	conf.maxInterval = max(conf.maxInterval, conf.interval)
+	return conf
+}
+
+// WithPollInterval sets the interval between polls. The interval grows with each
+// poll until it reaches maxInterval. By default polling starts every 5 seconds
+// and slows down to once a minute. Intervals of 0 or less are replaced by the
+// defaults, and a maxInterval below interval is raised to it.
+func WithPollInterval(interval, maxInterval time.Duration) WaitOption {
+	return func(w *waitConfig) {
+		w.interval = interval
+		w.maxInterval = maxInterval
+	}
+}
+
+// WithWaitTimeout sets the maximum time to wait in total. By default there is
+// no limit other than the deadline of the context.
+func WithWaitTimeout(d time.Duration) WaitOption {
+	return func(w *waitConfig) {
+		w.timeout = d
+	}
+}
+
+// WithFailureStatuses sets statuses which end the wait with a FailureStatusError,
+// for instance "destroying" when waiting for an instance to become "running".
+func WithFailureStatuses(statuses ...string) WaitOption {
+	return func(w *waitConfig) {
+		w.failureStatuses = statuses
+	}
+}
+
+// WithProgress sets a function called with the status observed at each poll.
+func WithProgress(f func(status string)) WaitOption {
+	return func(w *waitConfig) {
+		w.progress = f
+	}
+}
+
+// WaitForStatus polls the instance until it has one of the target statuses,
+// returning the last response from Aura. The context and WithWaitTimeout bound the
+// total time spent waiting, after which a WaitTimeoutError is returned.
+func (c *client) WaitForStatus(
+	ctx context.Context, id string, targetStatuses []string, options ...WaitOption,
+) (_ *GetResponse, err error) {
+	ctx, op := c.startOperation(ctx, "WaitForStatus", AttributeInstanceID.String(id))
+	defer op.end(&err)
+	var last *GetResponse
+	err = poll(ctx, id, targetStatuses, newWaitConfig(options), func(ctx context.Context) (string, error) {
+		resp, err := c.GetInstanceWithContext(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		last = resp
+		return resp.Data.Status, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return last, nil
+}
+
+// poll calls check until it returns one of the targets, a failure status or an error.
+func poll(
+	ctx context.Context, id string, targets []string, conf waitConfig,
+	check func(ctx context.Context) (string, error),
+) error {
+	if conf.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, conf.timeout)
+		defer cancel()
+	}
+	interval := conf.interval
+	var status string
+	for {
+		s, err := check(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return &WaitTimeoutError{ID: id, Targets: targets, LastStatus: status, Err: ctx.Err()}
+			}
+			return err
+		}
+		status = s
+		if conf.progress != nil {
+			conf.progress(status)
+		}
+		if slices.Contains(targets, status) {
+			return nil
+		}
+		if slices.Contains(conf.failureStatuses, status) {
+			return &FailureStatusError{ID: id, Targets: targets, Status: status}
+		}
+
+		timer := time.NewTimer(jitter(interval))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return &WaitTimeoutError{ID: id, Targets: targets, LastStatus: status, Err: ctx.Err()}
+		case <-timer.C:
+		}
+		interval = min(time.Duration(float64(interval)*pollBackoffFactor), conf.maxInterval)
+	}
+}
+
+// jitter spreads out polls from many waiters by randomly changing d by up to 20%.
+func jitter(d time.Duration) time.Duration {
+	//nolint:gosec // No need for cryptographically secure randomness
+	return time.Duration(float64(d) * (1 + pollJitter*(2*rand.Float64()-1)))
+}
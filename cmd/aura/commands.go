@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/indykite/aura-api-client/aura"
+)
+
+// commands holds the commands by group and name, i.e. "instances" and "list".
+var commands = map[string]map[string]*command{
+	"instances": {
+		"list":   {short: "List the instances of the tenant", setup: listInstances},
+		"get":    {args: []string{"id"}, short: "Show an instance", setup: getInstance},
+		"create": {short: "Create an instance", setup: createInstance},
+		"pause":  {args: []string{"id"}, short: "Pause an instance", setup: pauseInstance},
+		"resume": {args: []string{"id"}, short: "Resume a paused instance", setup: resumeInstance},
+		"delete": {args: []string{"id"}, short: "Destroy an instance", setup: deleteInstance},
+		"wait":   {args: []string{"id"}, short: "Wait for an instance to reach a status", setup: waitInstance},
+	},
+	"snapshots": {
+		"list":    {args: []string{"instance-id"}, short: "List the snapshots of an instance", setup: listSnapshots},
+		"get":     {args: []string{"instance-id", "snapshot-id"}, short: "Show a snapshot", setup: getSnapshot},
+		"create":  {args: []string{"instance-id"}, short: "Take a snapshot of an instance", setup: createSnapshot},
+		"restore": {args: []string{"instance-id", "snapshot-id"}, short: "Restore a snapshot", setup: restoreSnapshot},
+	},
+	"tenants": {
+		"list": {short: "List the tenants", setup: listTenants},
+		"get":  {args: []string{"id"}, short: "Show a tenant and its instance configurations", setup: getTenant},
+	},
+}
+
+const defaultWaitTimeout = 30 * time.Minute
+
+func listInstances(fs *flag.FlagSet) func(ctx context.Context, c *cli, args []string) error {
+	tenantID := fs.String("tenant", "", "only list the instances of this tenant")
+	return func(ctx context.Context, c *cli, args []string) error {
+		client, err := c.client(ctx)
+		if err != nil {
+			return err
+		}
+		resp, err := client.ListInstancesWithContext(ctx, *tenantID)
+		if err != nil {
+			return err
+		}
+		return c.print(resp.Data, func(w *tabwriter.Writer) {
+			fmt.Fprintln(w, "ID\tNAME\tTENANT\tCLOUD PROVIDER\tREGION\tTYPE")
+			for _, i := range resp.Data {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+					i.ID, i.Name, i.TenantID, i.CloudProvider, i.Region, i.InstanceType)
+			}
+		})
+	}
+}
+
+func getInstance(fs *flag.FlagSet) func(ctx context.Context, c *cli, args []string) error {
+	return func(ctx context.Context, c *cli, args []string) error {
+		client, err := c.client(ctx)
+		if err != nil {
+			return err
+		}
+		resp, err := client.GetInstanceWithContext(ctx, args[0])
+		if err != nil {
+			return err
+		}
+		return c.printInstance(resp.Data)
+	}
+}
+
+func createInstance(fs *flag.FlagSet) func(ctx context.Context, c *cli, args []string) error {
+	var r aura.CreateInstanceRequest
+	fs.StringVar(&r.Name, "name", "", "name of the instance (required)")
+	fs.StringVar(&r.TenantID, "tenant", "", "tenant of the instance, by default the configured tenant")
+	fs.Func("cloud-provider", "cloud provider: gcp, aws or azure (required)", func(s string) error {
+		r.CloudProvider = aura.CloudProvider(s)
+		return nil
+	})
+	fs.StringVar(&r.Region, "region", "", "region, i.e. europe-west1 (required)")
+	fs.Func("type", "instance type, i.e. enterprise-db (required)", func(s string) error {
+		r.InstanceType = aura.InstanceType(s)
+		return nil
+	})
+	fs.StringVar(&r.Memory, "memory", "", "amount of memory, i.e. 8GB (required)")
+	fs.StringVar(&r.Version, "version", "5", "Neo4j version")
+	fs.StringVar(&r.Storage, "storage", "", "amount of storage, i.e. 16GB")
+	fs.StringVar(&r.CustomerManagedKeyID, "customer-managed-key", "", "ID of the key to encrypt the instance with")
+	wait := fs.Bool("wait", false, "wait until the instance is running")
+	timeout := fs.Duration("timeout", defaultWaitTimeout, "maximum time to wait")
+	return func(ctx context.Context, c *cli, args []string) error {
+		for _, f := range [][2]string{
+			{"name", r.Name},
+			{"cloud-provider", string(r.CloudProvider)},
+			{"region", r.Region},
+			{"type", string(r.InstanceType)},
+			{"memory", r.Memory},
+		} {
+			if f[1] == "" {
+				return usagef("the -%s flag is required", f[0])
+			}
+		}
+		client, err := c.client(ctx)
+		if err != nil {
+			return err
+		}
+		resp, err := client.CreateInstanceFromRequestWithContext(ctx, r)
+		if err != nil {
+			return err
+		}
+		// The password is only shown once, so print it before waiting
+		err = c.print(resp.Data, func(w *tabwriter.Writer) {
+			fmt.Fprintln(w, "ID\tNAME\tUSERNAME\tPASSWORD\tCONNECTION URL")
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+				resp.Data.ID, resp.Data.Name, resp.Data.Username, resp.Data.Password, resp.Data.ConnectionURL)
+		})
+		if err != nil || !*wait {
+			return err
+		}
+		_, err = client.WaitForStatus(ctx, resp.Data.ID, []string{aura.StatusRunning},
+			aura.WithWaitTimeout(*timeout), aura.WithFailureStatuses(aura.StatusDestroying), c.progress())
+		return err
+	}
+}
+
+func pauseInstance(fs *flag.FlagSet) func(ctx context.Context, c *cli, args []string) error {
+	return changeInstance(fs, "pause", aura.StatusPaused, aura.Client.PauseInstanceWithContext)
+}
+
+func resumeInstance(fs *flag.FlagSet) func(ctx context.Context, c *cli, args []string) error {
+	return changeInstance(fs, "resume", aura.StatusRunning, aura.Client.ResumeInstanceWithContext)
+}
+
+// deleteInstance waits for the instance to be gone rather than for a status.
+func deleteInstance(fs *flag.FlagSet) func(ctx context.Context, c *cli, args []string) error {
+	wait := fs.Bool("wait", false, "wait until the instance is destroyed")
+	timeout := fs.Duration("timeout", defaultWaitTimeout, "maximum time to wait")
+	return func(ctx context.Context, c *cli, args []string) error {
+		client, err := c.client(ctx)
+		if err != nil {
+			return err
+		}
+		if err = client.DestroyInstanceWithContext(ctx, args[0]); err != nil {
+			return err
+		}
+		c.printf("Destroying instance %s\n", args[0])
+		if !*wait {
+			return nil
+		}
+		_, err = client.WaitForStatus(ctx, args[0], []string{"destroyed"},
+			aura.WithWaitTimeout(*timeout), c.progress())
+		if errors.Is(err, aura.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+}
+
+// changeInstance returns a command calling change, and optionally waiting
+// for the instance to reach the target status.
+func changeInstance(
+	fs *flag.FlagSet, verb, target string, change func(aura.Client, context.Context, string) error,
+) func(ctx context.Context, c *cli, args []string) error {
+	wait := fs.Bool("wait", false, "wait until the instance is "+target)
+	timeout := fs.Duration("timeout", defaultWaitTimeout, "maximum time to wait")
+	return func(ctx context.Context, c *cli, args []string) error {
+		client, err := c.client(ctx)
+		if err != nil {
+			return err
+		}
+		if err = change(client, ctx, args[0]); err != nil {
+			return err
+		}
+		if !*wait {
+			c.printf("Requested to %s instance %s\n", verb, args[0])
+			return nil
+		}
+		resp, err := client.WaitForStatus(ctx, args[0], []string{target},
+			aura.WithWaitTimeout(*timeout), aura.WithFailureStatuses(aura.StatusDestroying), c.progress())
+		if err != nil {
+			return err
+		}
+		return c.printInstance(resp.Data)
+	}
+}
+
+func waitInstance(fs *flag.FlagSet) func(ctx context.Context, c *cli, args []string) error {
+	status := fs.String("status", aura.StatusRunning, "comma separated statuses to wait for")
+	timeout := fs.Duration("timeout", defaultWaitTimeout, "maximum time to wait")
+	return func(ctx context.Context, c *cli, args []string) error {
+		client, err := c.client(ctx)
+		if err != nil {
+			return err
+		}
+		resp, err := client.WaitForStatus(ctx, args[0], strings.Split(*status, ","),
+			aura.WithWaitTimeout(*timeout), c.progress())
+		if err != nil {
+			return err
+		}
+		return c.printInstance(resp.Data)
+	}
+}
+
+func listSnapshots(fs *flag.FlagSet) func(ctx context.Context, c *cli, args []string) error {
+	date := fs.String("date", "", "only list snapshots taken on this day, YYYY-MM-DD, by default today")
+	return func(ctx context.Context, c *cli, args []string) error {
+		client, err := c.client(ctx)
+		if err != nil {
+			return err
+		}
+		resp, err := client.ListSnapshotsWithContext(ctx, args[0], *date)
+		if err != nil {
+			return err
+		}
+		return c.printSnapshots(resp.Data)
+	}
+}
+
+func getSnapshot(fs *flag.FlagSet) func(ctx context.Context, c *cli, args []string) error {
+	return func(ctx context.Context, c *cli, args []string) error {
+		client, err := c.client(ctx)
+		if err != nil {
+			return err
+		}
+		resp, err := client.GetSnapshotWithContext(ctx, args[0], args[1])
+		if err != nil {
+			return err
+		}
+		return c.printSnapshot(resp.Data)
+	}
+}
+
+func createSnapshot(fs *flag.FlagSet) func(ctx context.Context, c *cli, args []string) error {
+	return func(ctx context.Context, c *cli, args []string) error {
+		client, err := c.client(ctx)
+		if err != nil {
+			return err
+		}
+		resp, err := client.CreateSnapshotWithContext(ctx, args[0])
+		if err != nil {
+			return err
+		}
+		return c.print(resp.Data, func(w *tabwriter.Writer) {
+			fmt.Fprintln(w, "SNAPSHOT ID")
+			fmt.Fprintln(w, resp.Data.SnapshotID)
+		})
+	}
+}
+
+func restoreSnapshot(fs *flag.FlagSet) func(ctx context.Context, c *cli, args []string) error {
+	return func(ctx context.Context, c *cli, args []string) error {
+		client, err := c.client(ctx)
+		if err != nil {
+			return err
+		}
+		resp, err := client.RestoreSnapshotWithContext(ctx, args[0], args[1])
+		if err != nil {
+			return err
+		}
+		return c.printInstance(resp.Data)
+	}
+}
+
+func listTenants(fs *flag.FlagSet) func(ctx context.Context, c *cli, args []string) error {
+	return func(ctx context.Context, c *cli, args []string) error {
+		client, err := c.client(ctx)
+		if err != nil {
+			return err
+		}
+		resp, err := client.ListTenantsWithContext(ctx)
+		if err != nil {
+			return err
+		}
+		return c.print(resp.Data, func(w *tabwriter.Writer) {
+			fmt.Fprintln(w, "ID\tNAME")
+			for _, t := range resp.Data {
+				fmt.Fprintf(w, "%s\t%s\n", t.ID, t.Name)
+			}
+		})
+	}
+}
+
+func getTenant(fs *flag.FlagSet) func(ctx context.Context, c *cli, args []string) error {
+	return func(ctx context.Context, c *cli, args []string) error {
+		client, err := c.client(ctx)
+		if err != nil {
+			return err
+		}
+		resp, err := client.GetTenantWithContext(ctx, args[0])
+		if err != nil {
+			return err
+		}
+		return c.print(resp.Data, func(w *tabwriter.Writer) {
+			fmt.Fprintf(w, "%s (%s)\n\n", resp.Data.Name, resp.Data.ID)
+			fmt.Fprintln(w, "CLOUD PROVIDER\tREGION\tTYPE\tMEMORY\tSTORAGE\tVERSION")
+			for _, conf := range resp.Data.InstanceConfigurations {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+					conf.CloudProvider, conf.Region, conf.InstanceType, conf.Memory, conf.Storage, conf.Version)
+			}
+		})
+	}
+}
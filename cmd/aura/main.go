@@ -0,0 +1,308 @@
+// Command aura manages Neo4J Aura instances, snapshots and tenants.
+//
+// Credentials are read from the AURA_CLIENT_ID, AURA_CLIENT_SECRET and
+// AURA_TENANT_ID environment variables, or from a YAML config file with the
+// keys client_id, client_secret and tenant_id. The config file is read from
+// the path given by -config or AURA_CONFIG, and otherwise from aura/config.yaml
+// in the user config directory. Environment variables take precedence.
+//
+// Tokens are shared between invocations using a file in the user cache
+// directory, or the file given by token_cache or AURA_TOKEN_CACHE. Setting
+// it to "off" fetches a new token for each invocation.
+//
+// Failed commands exit with a status describing the error, see exitCode.
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/indykite/aura-api-client/aura"
+)
+
+// Exit statuses of the command.
+const (
+	exitOK           = 0
+	exitError        = 1
+	exitUsage        = 2
+	exitValidation   = 3 // Aura rejected the request as invalid
+	exitUnauthorized = 4 // Aura rejected the credentials or their permissions
+	exitNotFound     = 5
+	exitConflict     = 6
+	exitRateLimited  = 7
+	exitServer       = 8 // Aura failed with a 5xx status
+	exitWait         = 9 // Waiting for a status timed out or reached a failure status
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, os.Args[1:], os.Getenv, os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+// usageError is returned for invalid command lines.
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+func usagef(format string, args ...any) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+// cli holds what is shared by the commands.
+type cli struct {
+	stdout io.Writer
+	stderr io.Writer
+	getenv func(string) string
+
+	output     string
+	configPath string
+}
+
+// command is a command taking the named arguments. setup registers the flags
+// of the command and returns the function running it.
+type command struct {
+	args  []string
+	short string
+	setup func(fs *flag.FlagSet) func(ctx context.Context, c *cli, args []string) error
+}
+
+// run runs the command line, returning the exit status.
+func run(ctx context.Context, args []string, getenv func(string) string, stdout, stderr io.Writer) int {
+	c := &cli{stdout: stdout, stderr: stderr, getenv: getenv}
+	err := c.run(ctx, args)
+	if err == nil || errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	fmt.Fprintln(stderr, "aura:", err)
+	var usage *usageError
+	if errors.As(err, &usage) {
+		fmt.Fprintln(stderr, "Run 'aura help' for usage.")
+	}
+	return exitCode(err)
+}
+
+func (c *cli) run(ctx context.Context, args []string) error {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		c.usage()
+		return nil
+	}
+	group, ok := commands[args[0]]
+	if !ok {
+		return usagef("unknown command %q", args[0])
+	}
+	if len(args) == 1 {
+		return usagef("missing %s command", args[0])
+	}
+	cmd, ok := group[args[1]]
+	if !ok {
+		return usagef("unknown %s command %q", args[0], args[1])
+	}
+	name := args[0] + " " + args[1]
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.StringVar(&c.output, "o", "table", "output format: table, json or yaml")
+	fs.StringVar(&c.configPath, "config", "", "path of the config file")
+	runCmd := cmd.setup(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "Usage: aura %s [flags] %s\n\n%s\n\nFlags:\n", name, argNames(cmd.args), cmd.short)
+		fs.PrintDefaults()
+	}
+	positional, err := parseInterspersed(fs, args[2:])
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return &usageError{msg: err.Error()}
+	}
+	if len(positional) != len(cmd.args) {
+		return usagef("%s takes the arguments %s", name, argNames(cmd.args))
+	}
+	if !slices.Contains([]string{"table", "json", "yaml"}, c.output) {
+		return usagef("unknown output format %q", c.output)
+	}
+	return runCmd(ctx, c, positional)
+}
+
+// parseInterspersed parses flags given both before and after the positional
+// arguments, returning the positional arguments.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+func argNames(args []string) string {
+	names := make([]string, 0, len(args))
+	for _, a := range args {
+		names = append(names, "<"+a+">")
+	}
+	return strings.Join(names, " ")
+}
+
+func (c *cli) usage() {
+	fmt.Fprint(c.stderr, "Usage: aura <command> <subcommand> [flags] [arguments]\n\nCommands:\n")
+	groups := make([]string, 0, len(commands))
+	for g := range commands {
+		groups = append(groups, g)
+	}
+	sort.Strings(groups)
+	for _, g := range groups {
+		names := make([]string, 0, len(commands[g]))
+		for n := range commands[g] {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		for _, n := range names {
+			cmd := commands[g][n]
+			fmt.Fprintf(c.stderr, "  %-40s %s\n", strings.TrimSpace(g+" "+n+" "+argNames(cmd.args)), cmd.short)
+		}
+	}
+	fmt.Fprint(c.stderr, `
+Credentials are read from AURA_CLIENT_ID, AURA_CLIENT_SECRET and AURA_TENANT_ID,
+or from the config file given by -config or AURA_CONFIG. Tokens are shared
+between invocations unless AURA_TOKEN_CACHE is "off".
+Run 'aura <command> <subcommand> -h' for the flags of a command.
+`)
+}
+
+// config holds the settings read from the config file and environment.
+type config struct {
+	ClientID     string `yaml:"client_id"`
+	ClientSecret string `yaml:"client_secret"`
+	TenantID     string `yaml:"tenant_id"`
+	Endpoint     string `yaml:"endpoint"`
+	TokenCache   string `yaml:"token_cache"`
+}
+
+// loadConfig reads the config file, if any, and then the environment.
+func (c *cli) loadConfig() (config, error) {
+	var conf config
+	path := c.configPath
+	if path == "" {
+		path = c.getenv("AURA_CONFIG")
+	}
+	explicit := path != ""
+	if !explicit {
+		if dir, err := os.UserConfigDir(); err == nil {
+			path = filepath.Join(dir, "aura", "config.yaml")
+		}
+	}
+	if path != "" {
+		b, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			if err = yaml.Unmarshal(b, &conf); err != nil {
+				return conf, fmt.Errorf("reading config file %s: %w", path, err)
+			}
+		case explicit || !errors.Is(err, os.ErrNotExist):
+			return conf, err
+		}
+	}
+	for _, v := range []struct {
+		field *string
+		env   string
+	}{
+		{&conf.ClientID, "AURA_CLIENT_ID"},
+		{&conf.ClientSecret, "AURA_CLIENT_SECRET"},
+		{&conf.TenantID, "AURA_TENANT_ID"},
+		{&conf.Endpoint, "AURA_ENDPOINT"},
+		{&conf.TokenCache, "AURA_TOKEN_CACHE"},
+	} {
+		if value := c.getenv(v.env); value != "" {
+			*v.field = value
+		}
+	}
+	if conf.ClientID == "" || conf.ClientSecret == "" {
+		return conf, usagef("no credentials, set AURA_CLIENT_ID and AURA_CLIENT_SECRET or use a config file")
+	}
+	return conf, nil
+}
+
+// client returns a client using the configured credentials.
+func (c *cli) client(ctx context.Context) (aura.Client, error) {
+	conf, err := c.loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	var options []aura.Option
+	if conf.Endpoint != "" {
+		options = append(options, aura.WithEndpoint(conf.Endpoint))
+	}
+	if path := tokenCachePath(conf); path != "" {
+		options = append(options, aura.WithTokenCacheFile(path))
+	}
+	return aura.NewClient(ctx, conf.ClientID, conf.ClientSecret, conf.TenantID, options...)
+}
+
+// tokenCachePath returns the file to share tokens in, or an empty string if
+// tokens are not shared. By default each client ID and endpoint has a file of
+// its own in the user cache directory.
+func tokenCachePath(conf config) string {
+	switch conf.TokenCache {
+	case "off":
+		return ""
+	case "":
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			return ""
+		}
+		sum := sha256.Sum256([]byte(conf.Endpoint + "\n" + conf.ClientID))
+		return filepath.Join(dir, "aura", "token-"+hex.EncodeToString(sum[:8])+".json")
+	}
+	return conf.TokenCache
+}
+
+// exitCode returns the exit status describing err.
+func exitCode(err error) int {
+	var (
+		usage   *usageError
+		timeout *aura.WaitTimeoutError
+		failure *aura.FailureStatusError
+	)
+	switch {
+	case errors.As(err, &usage):
+		return exitUsage
+	case errors.As(err, &timeout), errors.As(err, &failure):
+		return exitWait
+	case errors.Is(err, aura.ErrValidation):
+		return exitValidation
+	case errors.Is(err, aura.ErrUnauthorized), errors.Is(err, aura.ErrForbidden):
+		return exitUnauthorized
+	case errors.Is(err, aura.ErrNotFound):
+		return exitNotFound
+	case errors.Is(err, aura.ErrConflict):
+		return exitConflict
+	case errors.Is(err, aura.ErrRateLimited):
+		return exitRateLimited
+	case errors.Is(err, aura.ErrServer):
+		return exitServer
+	}
+	return exitError
+}
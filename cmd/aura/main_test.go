@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/indykite/aura-api-client/aura"
+	"github.com/indykite/aura-api-client/aura/auratest"
+)
+
+var _ = Describe("aura", func() {
+	var (
+		server         *auratest.Server
+		env            map[string]string
+		stdout, stderr *bytes.Buffer
+	)
+	BeforeEach(func() {
+		server = auratest.NewServer()
+		DeferCleanup(server.Close)
+		// An empty config file keeps the tests from reading the config of the user
+		config := filepath.Join(GinkgoT().TempDir(), "config.yaml")
+		Expect(os.WriteFile(config, nil, 0o600)).To(Succeed())
+		env = map[string]string{
+			"AURA_CLIENT_ID":     auratest.DefaultClientID,
+			"AURA_CLIENT_SECRET": auratest.DefaultClientSecret,
+			"AURA_TENANT_ID":     auratest.DefaultTenantID,
+			"AURA_ENDPOINT":      server.URL,
+			"AURA_CONFIG":        config,
+			"AURA_TOKEN_CACHE":   filepath.Join(GinkgoT().TempDir(), "token.json"),
+		}
+		stdout, stderr = &bytes.Buffer{}, &bytes.Buffer{}
+	})
+	execute := func(args ...string) int {
+		stdout.Reset()
+		stderr.Reset()
+		return run(context.Background(), args, func(key string) string { return env[key] }, stdout, stderr)
+	}
+	It("should print usage", func() {
+		Expect(execute("help")).To(Equal(exitOK))
+		Expect(stderr.String()).To(ContainSubstring("instances create"))
+		Expect(execute("instances", "get", "-h")).To(Equal(exitOK))
+		Expect(stderr.String()).To(ContainSubstring("Usage: aura instances get [flags] <id>"))
+	})
+	It("should reject invalid command lines", func() {
+		Expect(execute("databases", "list")).To(Equal(exitUsage))
+		Expect(execute("instances", "get")).To(Equal(exitUsage))
+		Expect(execute("instances", "list", "-o", "xml")).To(Equal(exitUsage))
+		Expect(execute("instances", "create", "-name", "foo")).To(Equal(exitUsage))
+		Expect(stderr.String()).To(ContainSubstring("-cloud-provider"))
+	})
+	It("should manage instances", func() {
+		Expect(execute("instances", "create", "-name", "Production", "-cloud-provider", "gcp",
+			"-region", "europe-west1", "-type", "enterprise-db", "-memory", "8GB", "-wait")).To(Equal(exitOK))
+		Expect(stdout.String()).To(ContainSubstring("auratest-password-"))
+
+		Expect(execute("instances", "list", "-o", "json")).To(Equal(exitOK))
+		var instances []map[string]any
+		Expect(json.Unmarshal(stdout.Bytes(), &instances)).To(Succeed())
+		Expect(instances).To(HaveLen(1))
+		id := instances[0]["id"].(string)
+
+		Expect(execute("instances", "get", id, "-o", "yaml")).To(Equal(exitOK))
+		Expect(stdout.String()).To(ContainSubstring("status: running"))
+		Expect(stdout.String()).To(ContainSubstring("memory: 8GB"))
+
+		Expect(execute("instances", "pause", "-wait", id)).To(Equal(exitOK))
+		Expect(stdout.String()).To(MatchRegexp(`\n` + id + `\s+Production\s+paused`))
+
+		Expect(execute("instances", "delete", id, "-wait")).To(Equal(exitOK))
+		Expect(execute("instances", "get", id)).To(Equal(exitNotFound))
+	})
+	It("should manage snapshots", func() {
+		id := server.AddInstance(aura.GetResponseData{})
+		Expect(execute("snapshots", "create", id, "-o", "json")).To(Equal(exitOK))
+		var snapshot map[string]any
+		Expect(json.Unmarshal(stdout.Bytes(), &snapshot)).To(Succeed())
+		Expect(execute("snapshots", "get", id, snapshot["snapshot_id"].(string))).To(Equal(exitOK))
+		Expect(stdout.String()).To(ContainSubstring("Completed"))
+		Expect(execute("snapshots", "list", id)).To(Equal(exitOK))
+		Expect(stdout.String()).To(ContainSubstring(snapshot["snapshot_id"].(string)))
+		// A single snapshot is still listed as an array
+		Expect(execute("snapshots", "list", id, "-o", "json")).To(Equal(exitOK))
+		var snapshots []map[string]any
+		Expect(json.Unmarshal(stdout.Bytes(), &snapshots)).To(Succeed())
+		Expect(snapshots).To(HaveLen(1))
+		Expect(snapshots[0]["snapshot_id"]).To(Equal(snapshot["snapshot_id"]))
+	})
+	It("should show tenants", func() {
+		Expect(execute("tenants", "list")).To(Equal(exitOK))
+		Expect(stdout.String()).To(ContainSubstring(auratest.DefaultTenantID))
+		Expect(execute("tenants", "get", auratest.DefaultTenantID)).To(Equal(exitOK))
+		Expect(stdout.String()).To(ContainSubstring("enterprise-db"))
+	})
+	It("should read credentials from the config file", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "config.yaml")
+		Expect(os.WriteFile(path, []byte("client_id: "+auratest.DefaultClientID+"\n"+
+			"client_secret: "+auratest.DefaultClientSecret+"\n"+
+			"endpoint: "+server.URL+"\n"), 0o600)).To(Succeed())
+		env = map[string]string{"AURA_CONFIG": env["AURA_CONFIG"], "AURA_TOKEN_CACHE": env["AURA_TOKEN_CACHE"]}
+		Expect(execute("tenants", "list")).To(Equal(exitUsage))
+		Expect(execute("tenants", "list", "-config", path)).To(Equal(exitOK))
+	})
+	It("should share tokens between invocations", func() {
+		tokens := func() int {
+			n := 0
+			for _, r := range server.Requests() {
+				if r.Path == "/oauth/token" {
+					n++
+				}
+			}
+			return n
+		}
+		Expect(execute("tenants", "list")).To(Equal(exitOK))
+		Expect(execute("instances", "list")).To(Equal(exitOK))
+		Expect(tokens()).To(Equal(1))
+		env["AURA_TOKEN_CACHE"] = "off"
+		Expect(execute("tenants", "list")).To(Equal(exitOK))
+		Expect(execute("instances", "list")).To(Equal(exitOK))
+		Expect(tokens()).To(Equal(3))
+	})
+	It("should exit with a status describing the error", func() {
+		server.InjectFault(auratest.Fault{Path: "/v1/tenants", Status: http.StatusServiceUnavailable})
+		Expect(execute("tenants", "list")).To(Equal(exitServer))
+		server.InjectFault(auratest.Fault{Path: "/v1/instances", Status: http.StatusForbidden})
+		Expect(execute("instances", "list")).To(Equal(exitUnauthorized))
+		Expect(execute("instances", "resume", server.AddInstance(aura.GetResponseData{}))).To(Equal(exitOK))
+		Expect(execute("instances", "pause", "unknown")).To(Equal(exitNotFound))
+		Expect(stderr.String()).To(ContainSubstring("Instance not found"))
+	})
+})
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/indykite/aura-api-client/aura"
+)
+
+// print writes v in the output format, using table for the table format.
+func (c *cli) print(v any, table func(w *tabwriter.Writer)) error {
+	switch c.output {
+	case "json":
+		b, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(c.stdout, string(b))
+		return err
+	case "yaml":
+		// Go through JSON so the field names are the ones used by Aura
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		var generic any
+		if err = json.Unmarshal(b, &generic); err != nil {
+			return err
+		}
+		b, err = yaml.Marshal(generic)
+		if err != nil {
+			return err
+		}
+		_, err = c.stdout.Write(b)
+		return err
+	default:
+		w := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+		table(w)
+		return w.Flush()
+	}
+}
+
+// printf writes a message for people, which is left out of JSON and YAML output.
+func (c *cli) printf(format string, args ...any) {
+	if c.output == "table" {
+		fmt.Fprintf(c.stdout, format, args...)
+	}
+}
+
+// progress reports the statuses observed while waiting.
+func (c *cli) progress() aura.WaitOption {
+	return aura.WithProgress(func(status string) {
+		fmt.Fprintln(c.stderr, "Status:", status)
+	})
+}
+
+func (c *cli) printInstance(i aura.GetResponseData) error {
+	return c.print(i, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "ID\tNAME\tSTATUS\tTYPE\tMEMORY\tSTORAGE\tREGION\tCONNECTION URL")
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			i.ID, i.Name, i.Status, i.InstanceType, i.Memory, i.Storage, i.Region, i.ConnectionURL)
+	})
+}
+
+func (c *cli) printSnapshot(s aura.Snapshot) error {
+	return c.print(s, snapshotTable(s))
+}
+
+func (c *cli) printSnapshots(snapshots []aura.Snapshot) error {
+	return c.print(snapshots, snapshotTable(snapshots...))
+}
+
+func snapshotTable(snapshots ...aura.Snapshot) func(w *tabwriter.Writer) {
+	return func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "SNAPSHOT ID\tINSTANCE ID\tPROFILE\tSTATUS\tTIMESTAMP")
+		for _, s := range snapshots {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", s.SnapshotID, s.InstanceID, s.Profile, s.Status, s.Timestamp)
+		}
+	}
+}